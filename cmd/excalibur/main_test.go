@@ -71,7 +71,7 @@ func TestExcaliburE2E_Success(t *testing.T) {
 	}
 
 	// 4. Create and Execute the CLI Application
-	app := cliapp.NewApp("test-version", runExcalibur)
+	app := cliapp.NewApp("test-version", appRunners())
 	runErr := app.Run(ctx, args)
 	require.NoError(t, runErr, "app.Run failed unexpectedly")
 
@@ -124,7 +124,7 @@ func TestExcaliburE2E_MissingSQLFile(t *testing.T) {
 	}
 
 	// 4. Create and Execute the CLI Application
-	app := cliapp.NewApp("test-version", runExcalibur)
+	app := cliapp.NewApp("test-version", appRunners())
 	runErr := app.Run(ctx, args)
 	require.Error(t, runErr, "app.Run should have failed due to missing SQL file")
 
@@ -132,6 +132,36 @@ func TestExcaliburE2E_MissingSQLFile(t *testing.T) {
 	require.ErrorContains(t, runErr, "invalid_path.sql", "Error message should mention the missing file")
 }
 
+func TestExcaliburValidate_MissingSQLFile(t *testing.T) {
+	// No database needed: validation doesn't connect to the data source.
+	tempBaseDir := t.TempDir()
+	templatePath, _, outputPath := createTestFiles(
+		t,
+		tempBaseDir,
+		filepath.Join(testdataDir, "template_with_invalid_path.xlsx"),
+		"", // No expected file needed
+		"output_for_validate_test.xlsx",
+	)
+	tempQueriesDir := filepath.Join(filepath.Dir(templatePath), "sql")
+
+	args := []string{
+		"excalibur",
+		"--report-template-path", templatePath,
+		"--report-ref-col", "R",
+		"--report-queries-dir", tempQueriesDir,
+		"--report-output-path", outputPath,
+		"validate",
+	}
+
+	app := cliapp.NewApp("test-version", appRunners())
+	runErr := app.Run(t.Context(), args)
+	require.Error(t, runErr, "validate should fail due to the missing SQL file")
+	require.ErrorContains(t, runErr, "1 problem(s)")
+
+	_, statErr := os.Stat(outputPath)
+	require.ErrorIs(t, statErr, fs.ErrNotExist, "validate must not write the output file")
+}
+
 // --- Helper Functions ---
 
 func createTestFiles(
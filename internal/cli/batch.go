@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/nikoksr/assert-go"
+	"github.com/urfave/cli/v3"
+
+	"github.com/nikoksr/excalibur/internal/config"
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+// newBatchCommand creates the batch command, which generates all reports listed in a manifest. The logger is created
+// by the root command's Before hook, hence it is passed as a getter.
+func newBatchCommand(runner BatchFn, getLogger func() *slog.Logger) *cli.Command {
+	return &cli.Command{
+		Name:      "batch",
+		Usage:     "Generates multiple reports listed in a YAML or TOML manifest, sharing one set of data sources.",
+		ArgsUsage: "<manifest>",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "concurrency",
+				Usage: "Maximum number of reports generated at the same time. Overrides the manifest's setting.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar(config.EnvBatchConcurrency),
+				), // Env: EXCALIBUR_BATCH_CONCURRENCY
+				Value: config.DefaultBatchConcurrency,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			logger := getLogger()
+			assert.Assert(logger != nil, "Logger must not be nil")
+
+			manifestPath := cmd.Args().First()
+			if manifestPath == "" || cmd.Args().Len() > 1 {
+				return errors.New("batch requires exactly one manifest path argument")
+			}
+
+			// --- Load Manifest and Apply Flags/Env ---
+			logger.Debug("Loading batch manifest...", slog.String("path", manifestPath))
+			batch, err := config.LoadManifest(manifestPath, logger)
+			if err != nil {
+				logger.Error("Failed to load batch manifest", slog.String("error", err.Error()))
+				return fmt.Errorf("load batch manifest: %w", err)
+			}
+
+			if cmd.IsSet("dsn") || len(batch.DataSources) == 0 {
+				batch.DataSources = nil
+				for _, spec := range listSetting(cmd, "dsn") {
+					batch.DataSources = append(batch.DataSources, datasource.ParseNamedDSN(spec))
+				}
+			}
+			applyStatementTimeout(cmd, batch.DataSources)
+			if cmd.IsSet("concurrency") || batch.Concurrency == 0 {
+				batch.Concurrency = cmd.Int("concurrency")
+			}
+
+			// --- Normalize and Validate ---
+			logger.Debug("Normalizing batch configuration...")
+			normalizedBatch := config.NormalizeBatch(batch, logger)
+
+			logger.Debug("Validating batch configuration...")
+			if err := config.ValidateBatch(ctx, normalizedBatch, logger); err != nil {
+				logger.Error("Batch configuration validation failed", slog.String("error", err.Error()))
+				return fmt.Errorf("validate batch configuration: %w", err)
+			}
+
+			// --- Run Batch ---
+			logger.Debug("Batch configuration loaded and processed, executing batch.")
+			if err := runner(ctx, &normalizedBatch, logger); err != nil {
+				logger.Error("Batch execution failed", slog.String("error", err.Error()))
+				return err
+			}
+
+			logger.Debug("Batch finished successfully.")
+			return nil
+		},
+	}
+}
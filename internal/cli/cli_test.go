@@ -0,0 +1,134 @@
+package cli_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikoksr/excalibur/internal/cli"
+	"github.com/nikoksr/excalibur/internal/config"
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+// runConfig runs the root command with args and returns the configuration passed to the run function.
+func runConfig(t *testing.T, args ...string) config.Config {
+	t.Helper()
+
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "template.xlsx")
+	require.NoError(t, os.WriteFile(templatePath, []byte("template"), 0o600))
+
+	var got *config.Config
+	app := cli.NewApp("test", cli.Runners{
+		Run: func(_ context.Context, cfg *config.Config, _ cli.RunOptions, _ *slog.Logger) error {
+			got = cfg
+			return nil
+		},
+		Batch: func(context.Context, *config.Batch, *slog.Logger) error { return nil },
+		Validate: func(context.Context, *config.Config, cli.ValidateOptions, *slog.Logger) error {
+			return nil
+		},
+		Serve: func(context.Context, *config.Batch, cli.ServeOptions, *slog.Logger) error { return nil },
+	})
+
+	args = append([]string{
+		"excalibur",
+		"--report-template-path", templatePath,
+		"--report-queries-dir", dir,
+		"--report-output-path", filepath.Join(dir, "out.xlsx"),
+	}, args...)
+	require.NoError(t, app.Run(t.Context(), args))
+	require.NotNil(t, got, "run function must be called")
+
+	return *got
+}
+
+func TestApp_DSN(t *testing.T) {
+	const multiHostDSN = "postgres://user:secret@h1:5432,h2:5432/sales?target_session_attrs=read-write"
+
+	tests := []struct {
+		name string
+		args []string
+		env  string
+		want []datasource.Config
+	}{
+		{
+			name: "Multi-Host DSN Flag",
+			args: []string{"--dsn", multiHostDSN},
+			want: []datasource.Config{{DSN: multiHostDSN}},
+		},
+		{
+			name: "Repeated Named DSN Flags",
+			args: []string{"--dsn", "main=" + multiHostDSN, "--dsn", "local=sqlite:///tmp/local.db"},
+			want: []datasource.Config{
+				{Name: "main", DSN: multiHostDSN},
+				{Name: "local", DSN: "sqlite:///tmp/local.db"},
+			},
+		},
+		{
+			name: "Multi-Host DSN Env",
+			env:  multiHostDSN,
+			want: []datasource.Config{{DSN: multiHostDSN}},
+		},
+		{
+			name: "One DSN Per Line Env",
+			env:  "main=" + multiHostDSN + "\r\n\nlocal=sqlite:///tmp/local.db\n",
+			want: []datasource.Config{
+				{Name: "main", DSN: multiHostDSN},
+				{Name: "local", DSN: "sqlite:///tmp/local.db"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(config.EnvDSN, tt.env)
+
+			cfg := runConfig(t, tt.args...)
+
+			assert.Equal(t, tt.want, cfg.DataSources)
+		})
+	}
+}
+
+func TestApp_AllowedStatements(t *testing.T) {
+	cfg := runConfig(t, "--dsn", "sqlite:///tmp/local.db",
+		"--allowed-statements", "SELECT, WITH", "--allowed-statements", "EXPLAIN")
+
+	assert.Equal(t, []string{"SELECT", "WITH", "EXPLAIN"}, cfg.Report.AllowedStatements)
+}
+
+func TestApp_Params(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  string
+		want map[string]any
+	}{
+		{
+			name: "Comma In Flag Value",
+			args: []string{"--param", "regions=NORTH,SOUTH", "--param", "year:int=2026"},
+			want: map[string]any{"regions": "NORTH,SOUTH", "year": int64(2026)},
+		},
+		{
+			name: "Comma In Env Value",
+			env:  "regions=NORTH,SOUTH\nyear:int=2026\n",
+			want: map[string]any{"regions": "NORTH,SOUTH", "year": int64(2026)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(config.EnvReportParams, tt.env)
+
+			cfg := runConfig(t, append([]string{"--dsn", "sqlite:///tmp/local.db"}, tt.args...)...)
+
+			assert.Equal(t, tt.want, cfg.Report.Params)
+		})
+	}
+}
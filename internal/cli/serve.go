@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/nikoksr/assert-go"
+	"github.com/urfave/cli/v3"
+
+	"github.com/nikoksr/excalibur/internal/config"
+	"github.com/nikoksr/excalibur/internal/datasource"
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+// newServeCommand creates the serve command, which generates the reports listed in a manifest on request over HTTP.
+// The manifest's jobs are the available reports, its concurrency the number of reports generated at the same time.
+// The logger is created by the root command's Before hook, hence it is passed as a getter.
+func newServeCommand(runner ServeFn, getLogger func() *slog.Logger) *cli.Command {
+	return &cli.Command{
+		Name: "serve",
+		Usage: "Serves the reports listed in a YAML or TOML manifest over HTTP: GET /reports/<name>?param=value " +
+			"generates a report and returns the .xlsx file.",
+		ArgsUsage: "<manifest>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "addr",
+				Usage:   "TCP address to listen on.",
+				Sources: cli.NewValueSourceChain(cli.EnvVar(config.EnvServeAddr)), // Env: EXCALIBUR_SERVE_ADDR
+				Value:   config.DefaultServeAddr,
+			},
+			&cli.IntFlag{
+				Name: "concurrency",
+				Usage: "Maximum number of reports generated at the same time; further requests wait within their " +
+					"report's timeout. Overrides the manifest's setting.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar(config.EnvServeConcurrency),
+				), // Env: EXCALIBUR_SERVE_CONCURRENCY
+				Value: config.DefaultBatchConcurrency,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			logger := getLogger()
+			assert.Assert(logger != nil, "Logger must not be nil")
+
+			manifestPath := cmd.Args().First()
+			if manifestPath == "" || cmd.Args().Len() > 1 {
+				return errors.New("serve requires exactly one manifest path argument")
+			}
+
+			// --- Load Manifest and Apply Flags/Env ---
+			logger.Debug("Loading manifest...", slog.String("path", manifestPath))
+			batch, err := config.LoadManifest(manifestPath, logger)
+			if err != nil {
+				logger.Error("Failed to load manifest", slog.String("error", err.Error()))
+				return fmt.Errorf("load manifest: %w", err)
+			}
+
+			if cmd.IsSet("dsn") || len(batch.DataSources) == 0 {
+				batch.DataSources = nil
+				for _, spec := range listSetting(cmd, "dsn") {
+					batch.DataSources = append(batch.DataSources, datasource.ParseNamedDSN(spec))
+				}
+			}
+			applyStatementTimeout(cmd, batch.DataSources)
+			if cmd.IsSet("concurrency") || batch.Concurrency == 0 {
+				batch.Concurrency = cmd.Int("concurrency")
+			}
+
+			// --- Normalize and Validate ---
+			logger.Debug("Normalizing manifest configuration...")
+			normalizedBatch := config.NormalizeServedBatch(batch, logger)
+
+			logger.Debug("Validating manifest configuration...")
+			if err := config.ValidateBatch(ctx, normalizedBatch, logger); err != nil {
+				logger.Error("Manifest configuration validation failed", slog.String("error", err.Error()))
+				return fmt.Errorf("validate manifest configuration: %w", err)
+			}
+			// Unlike a batch, a server must not start with a broken report; requests could only ever fail.
+			for _, job := range normalizedBatch.Jobs {
+				if err := report.ValidateJob(ctx, job); err != nil {
+					logger.Error("Report configuration validation failed", slog.String("report", job.Name))
+					return fmt.Errorf("validate report %q: %w", job.Name, err)
+				}
+			}
+
+			// --- Serve ---
+			opts := ServeOptions{Addr: cmd.String("addr")}
+			if err := runner(ctx, &normalizedBatch, opts, logger); err != nil {
+				logger.Error("Server failed", slog.String("error", err.Error()))
+				return err
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/nikoksr/assert-go"
+	"github.com/urfave/cli/v3"
+
+	"github.com/nikoksr/excalibur/internal/config"
+)
+
+// newValidateCommand creates the validate command, which checks a template using the same configuration as the root
+// command. Unless schema checks are requested, no data source is connected to. The logger is created by the root
+// command's Before hook, hence it is passed as a getter.
+func newValidateCommand(runner ValidateFn, getLogger func() *slog.Logger) *cli.Command {
+	return &cli.Command{
+		Name: "validate",
+		Usage: "Checks a template without touching the database: referenced SQL files, their parameters and cell " +
+			"templates. Exits non-zero if problems are found.",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name: "schema",
+				Usage: "Also prepare every query against its data source, without executing it, and check the " +
+					"placeholders of its row against the result columns.",
+				Sources: cli.NewValueSourceChain(
+					cli.EnvVar(config.EnvValidateSchema),
+				), // Env: EXCALIBUR_VALIDATE_SCHEMA
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			logger := getLogger()
+			assert.Assert(logger != nil, "Logger must not be nil")
+
+			appConfig, err := loadConfig(cmd, logger)
+			if err != nil {
+				logger.Error("Failed to load configuration", slog.String("error", err.Error()))
+				return err
+			}
+
+			normalizedCfg, err := config.Normalize(appConfig, logger)
+			if err != nil {
+				logger.Error("Configuration normalization failed", slog.String("error", err.Error()))
+				return fmt.Errorf("normalize configuration: %w", err)
+			}
+
+			opts := ValidateOptions{Schema: cmd.Bool("schema")}
+
+			// Schema checks connect to the data sources, so they must be configured like for a regular run.
+			validate := config.ValidateOffline
+			if opts.Schema {
+				validate = config.Validate
+			}
+			if err := validate(ctx, normalizedCfg, logger); err != nil {
+				logger.Error("Configuration validation failed", slog.String("error", err.Error()))
+				return fmt.Errorf("validate configuration: %w", err)
+			}
+
+			return runner(ctx, &normalizedCfg, opts, logger)
+		},
+	}
+}
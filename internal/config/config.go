@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -16,19 +17,43 @@ import (
 )
 
 type Config struct {
-	DataSource datasource.Config
-	Report     report.Config
+	DataSources []datasource.Config
+	Report      report.Config
 }
 
 const (
 	EnvPrefix = "EXCALIBUR_"
 
+	EnvConfigFile             = EnvPrefix + "CONFIG"
 	EnvDSN                    = EnvPrefix + "DSN"
 	EnvReportTemplatePath     = EnvPrefix + "REPORT_TEMPLATE_PATH"
 	EnvReportDataSourceRefCol = EnvPrefix + "REPORT_DATASOURCE_REF_COL"
 	EnvReportQueriesDir       = EnvPrefix + "REPORT_QUERIES_DIR"
 	EnvReportOutputPath       = EnvPrefix + "REPORT_OUTPUT_PATH"
 	EnvReportTimeout          = EnvPrefix + "REPORT_TIMEOUT"
+	EnvReportParams           = EnvPrefix + "REPORT_PARAMS"
+	EnvReportTimezone         = EnvPrefix + "REPORT_TIMEZONE"
+	EnvReportDateFormat       = EnvPrefix + "REPORT_DATE_FORMAT"
+	EnvReportDateTimeFormat   = EnvPrefix + "REPORT_DATETIME_FORMAT"
+	EnvReportInfinity         = EnvPrefix + "REPORT_INFINITY"
+	EnvReportDecimals         = EnvPrefix + "REPORT_DECIMALS"
+	EnvReportCollectionFormat = EnvPrefix + "REPORT_COLLECTION_FORMAT"
+	EnvOnError                = EnvPrefix + "ON_ERROR"
+	EnvErrorMarker            = EnvPrefix + "ERROR_MARKER"
+	EnvKeepFailed             = EnvPrefix + "KEEP_FAILED"
+	EnvConcurrency            = EnvPrefix + "CONCURRENCY"
+	EnvCacheTTL               = EnvPrefix + "CACHE_TTL"
+	EnvCacheDir               = EnvPrefix + "CACHE_DIR"
+	EnvSnapshot               = EnvPrefix + "SNAPSHOT"
+	EnvStatementTimeout       = EnvPrefix + "STATEMENT_TIMEOUT"
+	EnvAllowedStatements      = EnvPrefix + "ALLOWED_STATEMENTS"
+	EnvBurst                  = EnvPrefix + "BURST"
+	EnvBatchConcurrency       = EnvPrefix + "BATCH_CONCURRENCY"
+	EnvServeAddr              = EnvPrefix + "SERVE_ADDR"
+	EnvServeConcurrency       = EnvPrefix + "SERVE_CONCURRENCY"
+	EnvValidateSchema         = EnvPrefix + "VALIDATE_SCHEMA"
+	EnvDryRun                 = EnvPrefix + "DRY_RUN"
+	EnvDryRunFormat           = EnvPrefix + "DRY_RUN_FORMAT"
 )
 
 const (
@@ -36,6 +61,8 @@ const (
 	DefaultReportRefColumn  = "R"       // Default Excel column for datasource references.
 	DefaultReportQueriesDir = "queries" // Default relative directory for SQL files.
 	DefaultReportOutputPath = "excalibur_report.xlsx"
+	DefaultConcurrency      = 1 // Default number of queries run at the same time.
+	DefaultServeAddr        = "localhost:8080"
 )
 
 func Validate(ctx context.Context, cfg Config, logger *slog.Logger) error {
@@ -45,9 +72,32 @@ func Validate(ctx context.Context, cfg Config, logger *slog.Logger) error {
 	logger.Debug("Validating configuration rules...")
 
 	validationProblems := make(map[string]string)
-	datasourceProblems := cfg.DataSource.Valid(ctx)
-	for key, problem := range datasourceProblems {
-		validationProblems["datasource."+key] = problem
+	validateDataSources(ctx, cfg.DataSources, validationProblems)
+
+	reportProblems := cfg.Report.Valid(ctx)
+	for key, problem := range reportProblems {
+		validationProblems["report."+key] = problem
+	}
+
+	if err := problemsError(validationProblems, logger); err != nil {
+		return err
+	}
+
+	logger.Debug("Configuration validation successful.")
+	return nil
+}
+
+// ValidateOffline validates the configuration like Validate, except that data sources are optional. It is meant for
+// commands that check a report without connecting to its data sources; configured data sources are still validated.
+func ValidateOffline(ctx context.Context, cfg Config, logger *slog.Logger) error {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(logger != nil, "logger must not be nil")
+
+	logger.Debug("Validating configuration rules (offline)...")
+
+	validationProblems := make(map[string]string)
+	if len(cfg.DataSources) > 0 {
+		validateDataSources(ctx, cfg.DataSources, validationProblems)
 	}
 
 	reportProblems := cfg.Report.Valid(ctx)
@@ -55,21 +105,64 @@ func Validate(ctx context.Context, cfg Config, logger *slog.Logger) error {
 		validationProblems["report."+key] = problem
 	}
 
-	if len(validationProblems) > 0 {
-		var errBuilder strings.Builder
-		errBuilder.WriteString("invalid configuration:")
-		for key, problem := range validationProblems {
-			errBuilder.WriteString(fmt.Sprintf("\n - %s: %s", key, problem))
-			logger.Debug("Validation issue", slog.String("field", key), slog.String("problem", problem))
-		}
-		return errors.New(errBuilder.String())
+	if err := problemsError(validationProblems, logger); err != nil {
+		return err
 	}
 
 	logger.Debug("Configuration validation successful.")
 	return nil
 }
 
+// validateDataSources adds the problems of the data source configurations to validationProblems.
+func validateDataSources(ctx context.Context, sources []datasource.Config, validationProblems map[string]string) {
+	if len(sources) == 0 {
+		validationProblems["datasources"] = "at least one data source must be configured"
+	}
+
+	seenSources := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		// Problems of named data sources are prefixed with their name, e.g. "datasource.warehouse.dsn".
+		keyPrefix := "datasource."
+		if source.Name != "" {
+			keyPrefix += source.Name + "."
+		}
+
+		if seenSources[source.SourceName()] {
+			validationProblems[keyPrefix+"name"] = fmt.Sprintf(
+				"data source %q configured more than once",
+				source.SourceName(),
+			)
+		}
+		seenSources[source.SourceName()] = true
+
+		datasourceProblems := source.Valid(ctx)
+		for key, problem := range datasourceProblems {
+			validationProblems[keyPrefix+key] = problem
+		}
+	}
+}
+
+// problemsError combines validation problems into a single error, or returns nil if there are none.
+func problemsError(validationProblems map[string]string, logger *slog.Logger) error {
+	if len(validationProblems) == 0 {
+		return nil
+	}
+
+	var errBuilder strings.Builder
+	errBuilder.WriteString("invalid configuration:")
+	for key, problem := range validationProblems {
+		errBuilder.WriteString(fmt.Sprintf("\n - %s: %s", key, problem))
+		logger.Debug("Validation issue", slog.String("field", key), slog.String("problem", problem))
+	}
+	return errors.New(errBuilder.String())
+}
+
 func Normalize(cfg Config, logger *slog.Logger) (Config, error) {
+	return normalize(cfg, true, logger)
+}
+
+// normalize normalizes cfg, rendering its output path if it is a template and renderOutput is set.
+func normalize(cfg Config, renderOutput bool, logger *slog.Logger) (Config, error) {
 	assert.Assert(logger != nil, "logger must not be nil")
 
 	logger.Debug("Normalizing configuration...")
@@ -93,6 +186,12 @@ func Normalize(cfg Config, logger *slog.Logger) (Config, error) {
 		return Config{}, err
 	}
 
+	if renderOutput {
+		normalizedCfg.Report.OutputPath, err = renderOutputPath(normalizedCfg.Report, time.Now(), logger)
+		if err != nil {
+			return Config{}, err
+		}
+	}
 	normalizedCfg.Report.OutputPath, err = makeAbsolutePath(normalizedCfg.Report.OutputPath, "output path", logger)
 	if err != nil {
 		return Config{}, err
@@ -107,10 +206,49 @@ func Normalize(cfg Config, logger *slog.Logger) (Config, error) {
 		return Config{}, err
 	}
 
+	if normalizedCfg.Report.CacheTTL > 0 {
+		if normalizedCfg.Report.CacheDir == "" {
+			normalizedCfg.Report.CacheDir, err = defaultCacheDir()
+			if err != nil {
+				return Config{}, err
+			}
+		}
+		normalizedCfg.Report.CacheDir, err = makeAbsolutePath(normalizedCfg.Report.CacheDir, "cache directory", logger)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
 	logger.Debug("Configuration normalization successful.")
 	return normalizedCfg, nil
 }
 
+// renderOutputPath renders the output path of a report if it is a template; see report.Config.RenderOutputPath. The
+// output paths of burst reports depend on the rows of the driver query and are rendered by report.Burst instead.
+func renderOutputPath(cfg report.Config, now time.Time, logger *slog.Logger) (string, error) {
+	if !report.IsOutputPathTemplate(cfg.OutputPath) || cfg.BurstQuery != "" {
+		return cfg.OutputPath, nil
+	}
+
+	rendered, err := cfg.RenderOutputPath(now)
+	if err != nil {
+		return "", fmt.Errorf("output path %q: %w", cfg.OutputPath, err)
+	}
+	logger.Info("Rendered output path template", slog.String("template", cfg.OutputPath), slog.String("path", rendered))
+
+	return rendered, nil
+}
+
+// defaultCacheDir returns the directory query results are cached in unless configured otherwise: "excalibur" in the
+// user's cache directory.
+func defaultCacheDir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("determine default cache directory: %w", err)
+	}
+	return filepath.Join(userCacheDir, "excalibur"), nil
+}
+
 func makeAbsolutePath(path, description string, logger *slog.Logger) (string, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/nikoksr/assert-go"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+// Names of the zones the TOML decoder assigns to local dates and date-times, which have no offset of their own.
+const (
+	tomlLocalDateZone     = "date-local"
+	tomlLocalDatetimeZone = "datetime-local"
+)
+
+// ErrUnsupportedConfigFormat indicates a configuration file with an extension other than .yaml, .yml or .toml.
+var ErrUnsupportedConfigFormat = errors.New("unsupported configuration file format")
+
+// File is the schema of a YAML or TOML configuration file. All fields are optional; unset fields fall back to
+// flags, environment variables or defaults.
+type File struct {
+	DataSources []FileDataSource `yaml:"datasources" toml:"datasources"`
+	Report      FileMainReport   `yaml:"report"      toml:"report"`
+}
+
+type FileDataSource struct {
+	Name             string `yaml:"name"              toml:"name"`
+	DSN              string `yaml:"dsn"               toml:"dsn"`
+	StatementTimeout string `yaml:"statement_timeout" toml:"statement_timeout"` // Go duration, e.g. "30s".
+}
+
+type FileReport struct {
+	TemplatePath        string `yaml:"template_path"          toml:"template_path"`
+	DataSourceRefColumn string `yaml:"data_source_ref_column" toml:"data_source_ref_column"`
+	QueriesDir          string `yaml:"queries_dir"            toml:"queries_dir"`
+	OutputPath          string `yaml:"output_path"            toml:"output_path"`
+	Timeout             string `yaml:"timeout"                toml:"timeout"` // Go duration, e.g. "10m".
+	Timezone            string `yaml:"timezone"               toml:"timezone"`
+	DateFormat          string `yaml:"date_format"            toml:"date_format"`
+	DateTimeFormat      string `yaml:"datetime_format"        toml:"datetime_format"`
+	Infinity            string `yaml:"infinity"               toml:"infinity"`
+	Decimals            string `yaml:"decimals"               toml:"decimals"`
+	CollectionFormat    string `yaml:"collection_format"      toml:"collection_format"`
+	OnError             string `yaml:"on_error"               toml:"on_error"`
+	ErrorMarker         string `yaml:"error_marker"           toml:"error_marker"`
+	KeepFailed          bool   `yaml:"keep_failed"            toml:"keep_failed"`
+	Concurrency         int    `yaml:"concurrency"            toml:"concurrency"` // Queries run at the same time.
+	CacheTTL            string `yaml:"cache_ttl"              toml:"cache_ttl"`   // Go duration; enables the disk cache.
+	CacheDir            string `yaml:"cache_dir"              toml:"cache_dir"`
+	Snapshot            bool   `yaml:"snapshot"               toml:"snapshot"`
+
+	// AllowedStatements restricts queries to the given statement types, e.g. ["SELECT", "WITH"].
+	AllowedStatements []string `yaml:"allowed_statements" toml:"allowed_statements"`
+
+	// Params maps parameter names to values. Keys may carry a type like the --param flag, e.g. "month:date".
+	Params map[string]any `yaml:"params" toml:"params"`
+}
+
+// FileMainReport is the report section of a configuration file. Unlike the jobs of a batch manifest, a report generated
+// on its own can be burst into one report per row of a driver query.
+type FileMainReport struct {
+	FileReport `yaml:",inline"`
+
+	Burst string `yaml:"burst" toml:"burst"` // Driver SQL file reference.
+}
+
+// LoadFile reads a YAML (.yaml, .yml) or TOML (.toml) configuration file and converts it into a Config. Unknown keys
+// are rejected to catch typos early. Relative paths, including those of SQLite databases, are resolved against the
+// directory of the file. Fields not present in the file are left at their zero value.
+func LoadFile(path string, logger *slog.Logger) (Config, error) {
+	assert.Assert(logger != nil, "logger must not be nil")
+
+	logger.Debug("Loading configuration file", slog.String("path", path))
+	var file File
+	if err := decodeFile(path, &file); err != nil {
+		return Config{}, err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("normalize configuration file path %q: %w", path, err)
+	}
+
+	cfg, err := file.toConfig(filepath.Dir(absPath))
+	if err != nil {
+		return Config{}, fmt.Errorf("configuration file %q: %w", path, err)
+	}
+
+	logger.Debug(
+		"Configuration file loaded",
+		slog.String("path", absPath),
+		slog.Int("datasource_count", len(cfg.DataSources)),
+	)
+	return cfg, nil
+}
+
+// decodeFile decodes a YAML (.yaml, .yml) or TOML (.toml) file into target, rejecting unknown keys. An empty file
+// leaves target unchanged.
+func decodeFile(path string, target any) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read configuration file %q: %w", path, err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(target); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parse YAML configuration file %q: %w", path, err)
+		}
+	case ".toml":
+		metadata, err := toml.Decode(string(content), target)
+		if err != nil {
+			return fmt.Errorf("parse TOML configuration file %q: %w", path, err)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parse TOML configuration file %q: unknown key %q", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("%w %q: expected .yaml, .yml or .toml", ErrUnsupportedConfigFormat, ext)
+	}
+
+	return nil
+}
+
+// toConfig converts the file content into a Config, resolving relative paths against baseDir.
+func (f File) toConfig(baseDir string) (Config, error) {
+	dataSources, err := dataSourceConfigs(f.DataSources, baseDir)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg := Config{DataSources: dataSources}
+
+	reportCfg, err := f.Report.toReportConfig(baseDir)
+	if err != nil {
+		return Config{}, fmt.Errorf("report: %w", err)
+	}
+	reportCfg.BurstQuery = f.Report.Burst
+	cfg.Report = reportCfg
+
+	return cfg, nil
+}
+
+// dataSourceConfigs converts data sources from a file, resolving relative SQLite database paths against baseDir.
+func dataSourceConfigs(sources []FileDataSource, baseDir string) ([]datasource.Config, error) {
+	var cfgs []datasource.Config
+	for i, source := range sources {
+		cfg := datasource.Config{Name: source.Name, DSN: source.DSN}
+		if source.StatementTimeout != "" {
+			timeout, err := time.ParseDuration(source.StatementTimeout)
+			if err != nil {
+				return nil, fmt.Errorf("datasources[%d]: statement_timeout: %w", i, err)
+			}
+			cfg.StatementTimeout = timeout
+		}
+		cfgs = append(cfgs, cfg.ResolveRelativeTo(baseDir))
+	}
+	return cfgs, nil
+}
+
+// toReportConfig converts the report settings into a report.Config, resolving relative paths against baseDir. Errors
+// are prefixed with the key of the offending setting.
+func (r FileReport) toReportConfig(baseDir string) (report.Config, error) {
+	cfg := report.Config{
+		TemplatePath:        resolvePath(baseDir, r.TemplatePath),
+		DataSourceRefColumn: r.DataSourceRefColumn,
+		QueriesDir:          resolvePath(baseDir, r.QueriesDir),
+		OutputPath:          resolvePath(baseDir, r.OutputPath),
+		Timezone:            r.Timezone,
+		DateFormat:          r.DateFormat,
+		DateTimeFormat:      r.DateTimeFormat,
+		Infinity:            r.Infinity,
+		Decimals:            r.Decimals,
+		CollectionFormat:    r.CollectionFormat,
+		OnError:             r.OnError,
+		ErrorMarker:         r.ErrorMarker,
+		KeepFailed:          r.KeepFailed,
+		Concurrency:         r.Concurrency,
+		CacheDir:            resolvePath(baseDir, r.CacheDir),
+		Snapshot:            r.Snapshot,
+		AllowedStatements:   r.AllowedStatements,
+	}
+
+	if r.Timeout != "" {
+		timeout, err := time.ParseDuration(r.Timeout)
+		if err != nil {
+			return report.Config{}, fmt.Errorf("timeout: %w", err)
+		}
+		cfg.Timeout = timeout
+	}
+	if r.CacheTTL != "" {
+		ttl, err := time.ParseDuration(r.CacheTTL)
+		if err != nil {
+			return report.Config{}, fmt.Errorf("cache_ttl: %w", err)
+		}
+		cfg.CacheTTL = ttl
+	}
+
+	if len(r.Params) > 0 {
+		cfg.Params = make(map[string]any, len(r.Params))
+		for key, value := range r.Params {
+			name, typedValue, err := fileParam(key, value)
+			if err != nil {
+				return report.Config{}, fmt.Errorf("params: %w", err)
+			}
+			if _, exists := cfg.Params[name]; exists {
+				return report.Config{}, fmt.Errorf("params: %w: %q specified more than once", report.ErrInvalidParam, name)
+			}
+			cfg.Params[name] = typedValue
+		}
+	}
+
+	return cfg, nil
+}
+
+// fileParam converts a parameter from a configuration file into the types produced by report.ParseParam. Keys of the
+// form "name:type" are parsed like the --param flag; otherwise the type is taken from the file's native value.
+func fileParam(key string, value any) (string, any, error) {
+	if strings.Contains(key, ":") {
+		raw := fmt.Sprint(value)
+		if t, ok := value.(time.Time); ok {
+			raw = t.Format(report.ParamDateLayout)
+		}
+		return report.ParseParam(key + "=" + raw)
+	}
+
+	switch v := value.(type) {
+	case string, bool, float64:
+		return key, v, nil
+	case int:
+		return key, int64(v), nil
+	case int64:
+		return key, v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return "", nil, fmt.Errorf("%w %q: value %d overflows int", report.ErrInvalidParam, key, v)
+		}
+		return key, int64(v), nil
+	case time.Time:
+		if zone := v.Location().String(); zone == tomlLocalDateZone || zone == tomlLocalDatetimeZone {
+			// TOML local dates carry no zone; treat them like date parameters from the command line.
+			v = time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.UTC)
+		}
+		return key, v, nil
+	default:
+		return "", nil, fmt.Errorf("%w %q: unsupported value type %T", report.ErrInvalidParam, key, value)
+	}
+}
+
+// resolvePath resolves a relative path against baseDir. Empty and absolute paths are returned unchanged.
+func resolvePath(baseDir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path/filepath"
+	"strings"
+
+	"github.com/nikoksr/assert-go"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+// DefaultBatchConcurrency is the number of batch jobs generated at the same time unless configured otherwise.
+const DefaultBatchConcurrency = 4
+
+// Batch is the configuration of a batch run: several reports generated with one shared set of data sources.
+type Batch struct {
+	DataSources []datasource.Config
+	Concurrency int          // Maximum number of jobs generated at the same time.
+	Jobs        []report.Job // Jobs in manifest order.
+}
+
+// Manifest is the schema of a YAML or TOML batch manifest.
+type Manifest struct {
+	DataSources []FileDataSource `yaml:"datasources" toml:"datasources"`
+	Concurrency int              `yaml:"concurrency" toml:"concurrency"`
+	Defaults    FileReport       `yaml:"defaults"    toml:"defaults"` // Settings shared by all jobs.
+	Jobs        []ManifestJob    `yaml:"jobs"        toml:"jobs"`
+}
+
+// ManifestJob is a single job of a batch manifest. Settings it leaves empty are taken from the manifest's defaults;
+// parameters are merged by name.
+type ManifestJob struct {
+	Name       string `yaml:"name" toml:"name"` // Defaults to the template file name without extension.
+	FileReport `yaml:",inline"`
+}
+
+// LoadManifest reads a YAML or TOML batch manifest and converts it into a Batch. Relative paths are resolved against
+// the directory of the manifest. Job settings missing from both the job and the manifest defaults fall back to the
+// regular defaults, except for the output path, which defaults to "<job name>.xlsx" next to the manifest so that jobs
+// don't overwrite each other.
+func LoadManifest(path string, logger *slog.Logger) (Batch, error) {
+	assert.Assert(logger != nil, "logger must not be nil")
+
+	logger.Debug("Loading batch manifest", slog.String("path", path))
+	var manifest Manifest
+	if err := decodeFile(path, &manifest); err != nil {
+		return Batch{}, err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return Batch{}, fmt.Errorf("normalize manifest path %q: %w", path, err)
+	}
+	baseDir := filepath.Dir(absPath)
+
+	dataSources, err := dataSourceConfigs(manifest.DataSources, baseDir)
+	if err != nil {
+		return Batch{}, fmt.Errorf("manifest %q: %w", path, err)
+	}
+	batch := Batch{
+		DataSources: dataSources,
+		Concurrency: manifest.Concurrency,
+	}
+	for i, job := range manifest.Jobs {
+		settings := job.withDefaults(manifest.Defaults)
+
+		name := job.Name
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(settings.TemplatePath), filepath.Ext(settings.TemplatePath))
+		}
+		if settings.OutputPath == "" && name != "" {
+			settings.OutputPath = name + ".xlsx"
+		}
+
+		reportCfg, err := settings.toReportConfig(baseDir)
+		if err != nil {
+			return Batch{}, fmt.Errorf("manifest %q: jobs[%d]: %w", path, i, err)
+		}
+		batch.Jobs = append(batch.Jobs, report.Job{Name: name, Config: reportCfg})
+	}
+
+	logger.Debug(
+		"Batch manifest loaded",
+		slog.String("path", absPath),
+		slog.Int("datasource_count", len(batch.DataSources)),
+		slog.Int("job_count", len(batch.Jobs)),
+	)
+	return batch, nil
+}
+
+// withDefaults returns the job's report settings with empty fields taken from defaults, and then from the regular
+// report defaults.
+func (j ManifestJob) withDefaults(defaults FileReport) FileReport {
+	settings := j.FileReport
+	settings.TemplatePath = firstNonEmpty(settings.TemplatePath, defaults.TemplatePath)
+	settings.DataSourceRefColumn = firstNonEmpty(
+		settings.DataSourceRefColumn,
+		defaults.DataSourceRefColumn,
+		DefaultReportRefColumn,
+	)
+	settings.QueriesDir = firstNonEmpty(settings.QueriesDir, defaults.QueriesDir, DefaultReportQueriesDir)
+	settings.OutputPath = firstNonEmpty(settings.OutputPath, defaults.OutputPath)
+	settings.Timeout = firstNonEmpty(settings.Timeout, defaults.Timeout, DefaultReportTimeout.String())
+	settings.Timezone = firstNonEmpty(settings.Timezone, defaults.Timezone)
+	settings.DateFormat = firstNonEmpty(settings.DateFormat, defaults.DateFormat)
+	settings.DateTimeFormat = firstNonEmpty(settings.DateTimeFormat, defaults.DateTimeFormat)
+	settings.Infinity = firstNonEmpty(settings.Infinity, defaults.Infinity)
+	settings.Decimals = firstNonEmpty(settings.Decimals, defaults.Decimals)
+	settings.CollectionFormat = firstNonEmpty(settings.CollectionFormat, defaults.CollectionFormat)
+	settings.OnError = firstNonEmpty(settings.OnError, defaults.OnError)
+	settings.ErrorMarker = firstNonEmpty(settings.ErrorMarker, defaults.ErrorMarker)
+	settings.CacheTTL = firstNonEmpty(settings.CacheTTL, defaults.CacheTTL)
+	settings.CacheDir = firstNonEmpty(settings.CacheDir, defaults.CacheDir)
+	settings.KeepFailed = settings.KeepFailed || defaults.KeepFailed
+	settings.Snapshot = settings.Snapshot || defaults.Snapshot
+	if settings.Concurrency == 0 {
+		settings.Concurrency = defaults.Concurrency
+	}
+	if len(settings.AllowedStatements) == 0 {
+		settings.AllowedStatements = defaults.AllowedStatements
+	}
+
+	params := make(map[string]any, len(defaults.Params)+len(j.Params))
+	maps.Copy(params, defaults.Params)
+	maps.Copy(params, j.Params)
+	settings.Params = params
+
+	return settings
+}
+
+// NormalizeBatch normalizes the report configuration of every job like Normalize does for a single report. A job whose
+// configuration can't be normalized, e.g. because its output path template uses an undefined parameter, keeps its
+// configuration as is and gets the error as report.Job.Err, so that it fails on its own when the batch runs.
+func NormalizeBatch(batch Batch, logger *slog.Logger) Batch {
+	return normalizeBatch(batch, true, logger)
+}
+
+// NormalizeServedBatch is like NormalizeBatch for a batch whose reports are served rather than written: output path
+// templates are left unrendered, since served reports are never written to their output path and the templates may
+// use parameters that only requests supply.
+func NormalizeServedBatch(batch Batch, logger *slog.Logger) Batch {
+	return normalizeBatch(batch, false, logger)
+}
+
+func normalizeBatch(batch Batch, renderOutput bool, logger *slog.Logger) Batch {
+	assert.Assert(logger != nil, "logger must not be nil")
+
+	normalized := batch
+	normalized.Jobs = make([]report.Job, 0, len(batch.Jobs))
+	for _, job := range batch.Jobs {
+		cfg, err := normalize(Config{Report: job.Config}, renderOutput, logger)
+		if err != nil {
+			logger.Warn("Job configuration normalization failed", slog.String("job", job.Name),
+				slog.String("error", err.Error()))
+			normalized.Jobs = append(normalized.Jobs, report.Job{Name: job.Name, Config: job.Config, Err: err})
+			continue
+		}
+		normalized.Jobs = append(normalized.Jobs, report.Job{Name: job.Name, Config: cfg.Report})
+	}
+
+	return normalized
+}
+
+// ValidateBatch validates the parts of a batch that all jobs depend on: the data sources, the concurrency and the job
+// names and output paths. The report configuration of each job is validated when the job runs, so that one broken job
+// doesn't prevent the others from being generated.
+func ValidateBatch(ctx context.Context, batch Batch, logger *slog.Logger) error {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(logger != nil, "logger must not be nil")
+
+	logger.Debug("Validating batch configuration rules...")
+
+	validationProblems := make(map[string]string)
+	validateDataSources(ctx, batch.DataSources, validationProblems)
+
+	if batch.Concurrency <= 0 {
+		validationProblems["concurrency"] = "must be a positive number"
+	}
+	if len(batch.Jobs) == 0 {
+		validationProblems["jobs"] = "at least one job must be configured"
+	}
+
+	seenNames := make(map[string]bool, len(batch.Jobs))
+	seenOutputs := make(map[string]string, len(batch.Jobs))
+	for i, job := range batch.Jobs {
+		key := fmt.Sprintf("jobs[%d]", i)
+		if job.Name == "" {
+			validationProblems[key+".name"] = "must not be empty"
+		} else if seenNames[job.Name] {
+			validationProblems[key+".name"] = fmt.Sprintf("job %q configured more than once", job.Name)
+		}
+		seenNames[job.Name] = true
+
+		if job.Err != nil {
+			continue // Fails on its own when the batch runs, see NormalizeBatch.
+		}
+		if other, exists := seenOutputs[job.Config.OutputPath]; exists {
+			validationProblems[key+".output_path"] = fmt.Sprintf("same output path as job %q", other)
+		}
+		seenOutputs[job.Config.OutputPath] = job.Name
+	}
+
+	if err := problemsError(validationProblems, logger); err != nil {
+		return err
+	}
+
+	logger.Debug("Batch configuration validation successful.")
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
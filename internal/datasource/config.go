@@ -2,20 +2,81 @@ package datasource
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 type Config struct {
-	DSN string
+	Name string // Optional name used to reference the data source, e.g. "warehouse" in "warehouse:sales/total.sql".
+	DSN  string
+
+	// StatementTimeout is the maximum duration of a single query; zero means no limit. PostgreSQL enforces it on the
+	// server as statement_timeout, other databases by canceling the query.
+	StatementTimeout time.Duration
+}
+
+// SourceName returns the configured name, or DefaultSourceName for unnamed data sources.
+func (c Config) SourceName() string {
+	if c.Name == "" {
+		return DefaultSourceName
+	}
+	return c.Name
+}
+
+// Scheme returns the lowercase scheme of the DSN (e.g. "postgres" or "sqlite"), or an empty string for DSNs without
+// one.
+func (c Config) Scheme() string {
+	scheme, _ := splitScheme(c.DSN)
+	return scheme
+}
+
+// ResolveRelativeTo returns a copy of the config in which a relative SQLite database path is resolved against baseDir.
+// All other DSNs are returned unchanged.
+func (c Config) ResolveRelativeTo(baseDir string) Config {
+	path := sqliteDatabasePath(c.DSN)
+	if c.Scheme() != SchemeSQLite || path == "" || filepath.IsAbs(path) {
+		return c
+	}
+
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(c.DSN), "://")
+	resolved := scheme + "://" + filepath.ToSlash(filepath.Join(baseDir, path))
+	if _, params, found := strings.Cut(rest, "?"); found {
+		resolved += "?" + params
+	}
+	c.DSN = resolved
+
+	return c
 }
 
 func (c Config) Valid(_ context.Context) map[string]string {
 	problems := make(map[string]string)
+	if c.Name != "" && !IsValidSourceName(c.Name) {
+		problems["name"] = fmt.Sprintf(
+			"must start with a letter or underscore and contain only letters, digits, '_' or '-', got: %s",
+			c.Name,
+		)
+	}
+
+	if c.StatementTimeout < 0 {
+		problems["statement_timeout"] = fmt.Sprintf("must not be negative, got: %s", c.StatementTimeout)
+	}
+
 	if strings.TrimSpace(c.DSN) == "" {
 		problems["dsn"] = "must not be empty"
+		return problems
 	}
 
-	// TODO: ?; Validate DSN format
+	if scheme := c.Scheme(); !isSupportedScheme(scheme) {
+		problems["dsn"] = fmt.Sprintf("unsupported scheme %q", scheme)
+	} else if scheme == SchemeSQLite && sqliteDatabasePath(c.DSN) == "" {
+		problems["dsn"] = "sqlite DSN must contain a database path (e.g. sqlite:///path/to/file.db)"
+	} else if scheme == SchemeMySQL || scheme == SchemeMariaDB {
+		if _, err := mysqlDriverConfig(c.DSN); err != nil {
+			problems["dsn"] = fmt.Sprintf("invalid %s DSN: %v", scheme, err)
+		}
+	}
 
 	return problems
 }
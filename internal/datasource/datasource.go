@@ -3,6 +3,7 @@ package datasource
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 var (
@@ -17,7 +18,84 @@ var (
 	ErrDataSourceClosed = errors.New("data source is closed")
 )
 
+// DataSource executes report queries. Queries may reference named parameters as ":name", which are bound from params as
+// real query arguments; referencing a parameter missing from params fails with ErrUndefinedParameter.
 type DataSource interface {
-	FetchData(ctx context.Context, query string) (map[string]any, error)
+	// FetchData executes the query and returns its single result row as a map of column names to values.
+	FetchData(ctx context.Context, query string, params map[string]any) (map[string]any, error)
+	// FetchRows executes the query and returns all result rows in order. An empty result is not an error.
+	FetchRows(ctx context.Context, query string, params map[string]any) ([]map[string]any, error)
 	Close(ctx context.Context) error
 }
+
+// Column describes a column of a query's result.
+type Column struct {
+	Name         string
+	DatabaseType string // Database type name, e.g. "numeric"; empty if unknown.
+}
+
+// Describer is implemented by data sources that can determine the result columns of a query without executing it.
+type Describer interface {
+	// DescribeQuery prepares the query, binding params like FetchData, and returns its result columns in order.
+	DescribeQuery(ctx context.Context, query string, params map[string]any) ([]Column, error)
+}
+
+// Snapshotter is implemented by data sources that can run queries against a consistent snapshot of the database.
+type Snapshotter interface {
+	// Snapshot takes a snapshot and returns a data source whose queries all see the database as of that moment, even
+	// when run concurrently. Closing the returned data source releases the snapshot, not the data source it was taken
+	// of.
+	Snapshot(ctx context.Context) (DataSource, error)
+}
+
+// Pinger is implemented by data sources that can check whether their database is reachable.
+type Pinger interface {
+	// Ping verifies that the database can be reached, without running a report query.
+	Ping(ctx context.Context) error
+}
+
+// InfiniteTime is returned for the special date and timestamp values "infinity" and "-infinity", which have no
+// time.Time equivalent.
+type InfiniteTime struct {
+	Negative bool
+}
+
+func (t InfiniteTime) String() string {
+	if t.Negative {
+		return "-infinity"
+	}
+	return "infinity"
+}
+
+type timezoneKey struct{}
+
+// WithTimezone returns a context that makes data sources return timestamps with a time zone, such as Postgres'
+// TIMESTAMPTZ and MySQL's TIMESTAMP, in loc. Without it they are returned in UTC. Values without a time zone, like
+// dates, keep their wall clock time either way.
+func WithTimezone(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, timezoneKey{}, loc)
+}
+
+type exactDecimalsKey struct{}
+
+// WithExactDecimals returns a context that makes data sources return exact numeric types, such as Postgres' NUMERIC
+// and MySQL's DECIMAL, as decimal.Decimal. Without it they are converted to float64, which may lose precision.
+func WithExactDecimals(ctx context.Context) context.Context {
+	return context.WithValue(ctx, exactDecimalsKey{}, true)
+}
+
+// conversionOptions controls how data sources convert database values into Go values.
+type conversionOptions struct {
+	location      *time.Location // Time zone of timestamps with a time zone.
+	exactDecimals bool           // Whether exact numeric types become decimal.Decimal instead of float64.
+}
+
+// conversionOptionsFrom returns the conversion options set with WithTimezone and WithExactDecimals.
+func conversionOptionsFrom(ctx context.Context) conversionOptions {
+	opts := conversionOptions{location: time.UTC}
+	if loc, ok := ctx.Value(timezoneKey{}).(*time.Location); ok && loc != nil {
+		opts.location = loc
+	}
+	opts.exactDecimals, _ = ctx.Value(exactDecimalsKey{}).(bool)
+	return opts
+}
@@ -0,0 +1,27 @@
+package datasource
+
+import "time"
+
+// Exported aliases of unexported functions for the external datasource_test package.
+var (
+	MySQLDriverConfig = mysqlDriverConfig
+	ConvertMySQLValue = convertMySQLValue
+	BindParams        = bindParams
+)
+
+// ConversionOptions is exported for the external datasource_test package.
+type ConversionOptions = conversionOptions
+
+// NewConversionOptions returns conversion options for the external datasource_test package.
+func NewConversionOptions(location *time.Location, exactDecimals bool) ConversionOptions {
+	return conversionOptions{location: location, exactDecimals: exactDecimals}
+}
+
+// PlaceholderStyle is exported for the external datasource_test package.
+type PlaceholderStyle = placeholderStyle
+
+// Exported placeholder styles for the external datasource_test package.
+const (
+	PlaceholderDollar   = placeholderDollar
+	PlaceholderQuestion = placeholderQuestion
+)
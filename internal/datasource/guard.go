@@ -0,0 +1,101 @@
+package datasource
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrMultipleStatements indicates a query consisting of more than one statement. Report queries must be a single
+	// statement, so that a query file can't smuggle in a statement after the one it is meant to run.
+	ErrMultipleStatements = errors.New("query must consist of a single statement")
+
+	// ErrStatementNotAllowed indicates a query whose statement type isn't in the configured allowlist.
+	ErrStatementNotAllowed = errors.New("statement type not allowed")
+)
+
+// StatementType returns the uppercase first keyword of a query, e.g. "SELECT" or "WITH", skipping leading comments and
+// opening parentheses. It returns an empty string if the query has no keyword.
+func StatementType(query string) string {
+	for i := 0; i < len(query); {
+		if end := skipQuoted(query, i, false); end > i {
+			if query[i] != '-' && query[i] != '/' {
+				return "" // A literal or quoted identifier before any keyword.
+			}
+			i = end // Comment
+			continue
+		}
+
+		if isSpace(query[i]) || query[i] == '(' {
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(query) && isIdentPart(query[end]) {
+			end++
+		}
+		return strings.ToUpper(query[i:end])
+	}
+
+	return ""
+}
+
+// CheckStatement verifies before a query runs that it is a single statement and, if allowed isn't empty, that its
+// statement type is one of allowed, compared case-insensitively. Since the quoting rules of the database the query
+// runs on aren't known here, a query only counts as multiple statements if it does so with and without backslash
+// escapes in string literals; data sources check again with their actual rules when the query runs.
+func CheckStatement(query string, allowed []string) error {
+	if hasMultipleStatements(query, false) && hasMultipleStatements(query, true) {
+		return ErrMultipleStatements
+	}
+
+	if len(allowed) == 0 {
+		return nil
+	}
+	statementType := StatementType(query)
+	if !slices.ContainsFunc(allowed, func(a string) bool { return strings.EqualFold(a, statementType) }) {
+		return fmt.Errorf("%w: %s (allowed: %s)", ErrStatementNotAllowed, cmp.Or(statementType, "unknown"),
+			strings.Join(allowed, ", "))
+	}
+
+	return nil
+}
+
+// checkSingleStatement returns ErrMultipleStatements if the query consists of more than one statement.
+func checkSingleStatement(query string, backslashEscapes bool) error {
+	if hasMultipleStatements(query, backslashEscapes) {
+		return ErrMultipleStatements
+	}
+	return nil
+}
+
+// hasMultipleStatements reports whether a semicolon outside of literals, quoted identifiers and comments is followed by
+// anything but whitespace, comments and further semicolons.
+func hasMultipleStatements(query string, backslashEscapes bool) bool {
+	terminated := false
+	for i := 0; i < len(query); {
+		if end := skipQuoted(query, i, backslashEscapes); end > i {
+			isComment := query[i] == '-' || query[i] == '/'
+			if terminated && !isComment {
+				return true
+			}
+			i = end
+			continue
+		}
+
+		switch {
+		case query[i] == ';':
+			terminated = true
+		case isSpace(query[i]):
+		case terminated:
+			return true
+		}
+		i++
+	}
+
+	return false
+}
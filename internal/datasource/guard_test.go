@@ -0,0 +1,89 @@
+package datasource_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+func TestStatementType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "select 1", want: "SELECT"},
+		{query: "  -- totals\n/* yearly */ WITH t AS (SELECT 1) SELECT * FROM t", want: "WITH"},
+		{query: "(SELECT 1) UNION (SELECT 2)", want: "SELECT"},
+		{query: "delete from t", want: "DELETE"},
+		{query: "'literal'", want: ""},
+		{query: "-- only a comment", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, datasource.StatementType(tt.query))
+		})
+	}
+}
+
+func TestCheckStatement(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		query   string
+		allowed []string
+		wantErr error
+	}{
+		{name: "Single Statement", query: "SELECT 1"},
+		{name: "Trailing Semicolon And Comment", query: "SELECT 1; -- done\n;"},
+		{name: "Semicolon In Literal", query: "SELECT 'a; DROP TABLE t' AS s"},
+		{name: "Semicolon In Identifier And Comment", query: "SELECT 1 AS \"a;b\" /* x; y */"},
+		{name: "Multiple Statements", query: "SELECT 1; DELETE FROM t", wantErr: datasource.ErrMultipleStatements},
+		{
+			name:    "Multiple Statements Without Spaces",
+			query:   "SELECT 1;SELECT 2",
+			wantErr: datasource.ErrMultipleStatements,
+		},
+		{
+			// Under backslash escapes the literal ends after "b", under standard quoting it is still open. A query
+			// only counts as multiple statements if it does under both rules; data sources check again when it runs.
+			name:  "Backslash In Literal",
+			query: `SELECT 'a\'; SELECT 'b'`,
+		},
+		{name: "Allowed Type", query: "with t as (select 1) select * from t", allowed: []string{"SELECT", "with"}},
+		{
+			name:    "Disallowed Type",
+			query:   "UPDATE t SET x = 1",
+			allowed: []string{"SELECT", "WITH"},
+			wantErr: datasource.ErrStatementNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := datasource.CheckStatement(tt.query, tt.allowed)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCheckStatement_NamesType(t *testing.T) {
+	t.Parallel()
+
+	err := datasource.CheckStatement("DELETE FROM t", []string{"SELECT"})
+	require.ErrorIs(t, err, datasource.ErrStatementNotAllowed)
+	assert.EqualError(t, err, "statement type not allowed: DELETE (allowed: SELECT)")
+}
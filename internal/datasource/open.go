@@ -0,0 +1,75 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/nikoksr/assert-go"
+)
+
+// Supported DSN schemes. A DSN without a scheme (e.g. "host=localhost user=excalibur") is treated as a PostgreSQL
+// keyword/value connection string.
+const (
+	SchemePostgres   = "postgres"
+	SchemePostgreSQL = "postgresql"
+	SchemeSQLite     = "sqlite"
+	SchemeMySQL      = "mysql"
+	SchemeMariaDB    = "mariadb"
+)
+
+// ErrUnsupportedScheme indicates that no DataSource implementation exists for the scheme of a DSN.
+var ErrUnsupportedScheme = errors.New("unsupported DSN scheme")
+
+// Open creates the DataSource implementation matching the scheme of the configured DSN.
+func Open(ctx context.Context, cfg Config, logger *slog.Logger) (DataSource, error) {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(logger != nil, "logger must not be nil")
+
+	scheme := cfg.Scheme()
+	logger.Debug("Selecting data source by DSN scheme", slog.String("scheme", scheme))
+
+	switch scheme {
+	case "", SchemePostgres, SchemePostgreSQL:
+		source, err := NewPostgresDataSource(ctx, cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		return source, nil
+	case SchemeSQLite:
+		source, err := NewSQLiteDataSource(ctx, cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		return source, nil
+	case SchemeMySQL, SchemeMariaDB:
+		source, err := NewMySQLDataSource(ctx, cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		return source, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedScheme, scheme)
+	}
+}
+
+// isSupportedScheme reports whether Open knows a DataSource implementation for the given scheme.
+func isSupportedScheme(scheme string) bool {
+	switch scheme {
+	case "", SchemePostgres, SchemePostgreSQL, SchemeSQLite, SchemeMySQL, SchemeMariaDB:
+		return true
+	default:
+		return false
+	}
+}
+
+// splitScheme splits a URL-like DSN into its lowercase scheme and the remainder. DSNs without "://" have no scheme.
+func splitScheme(dsn string) (string, string) {
+	scheme, rest, found := strings.Cut(strings.TrimSpace(dsn), "://")
+	if !found {
+		return "", dsn
+	}
+	return strings.ToLower(scheme), rest
+}
@@ -0,0 +1,219 @@
+package datasource
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrUndefinedParameter indicates that a query references a named parameter that was not provided.
+var ErrUndefinedParameter = errors.New("undefined query parameter")
+
+// placeholderStyle determines how named parameters are rewritten into the driver's positional placeholders.
+type placeholderStyle int
+
+const (
+	// placeholderDollar rewrites parameters to $1, $2, ... and binds each distinct parameter once (PostgreSQL).
+	placeholderDollar placeholderStyle = iota
+	// placeholderQuestion rewrites every occurrence to ? and binds one argument per occurrence (MySQL, SQLite).
+	placeholderQuestion
+)
+
+// bindParams rewrites the named parameters in a query (":name") into positional placeholders of the given style and
+// returns the rewritten query with its arguments. Parameters inside string literals, quoted identifiers, comments and
+// PostgreSQL dollar-quoted strings are left untouched, as are "::" casts. Backslash escapes in string literals are
+// honored if backslashEscapes is set (MySQL).
+func bindParams(
+	query string,
+	params map[string]any,
+	style placeholderStyle,
+	backslashEscapes bool,
+) (string, []any, error) {
+	var (
+		out       strings.Builder
+		args      []any
+		positions = make(map[string]int) // Parameter name -> 1-based position for placeholderDollar.
+		undefined []string
+	)
+	out.Grow(len(query))
+
+	for i := 0; i < len(query); {
+		if end := skipQuoted(query, i, backslashEscapes); end > i {
+			out.WriteString(query[i:end])
+			i = end
+			continue
+		}
+
+		name, end := parseParamName(query, i)
+		if name == "" {
+			out.WriteByte(query[i])
+			i++
+			continue
+		}
+		i = end
+
+		value, ok := params[name]
+		if !ok {
+			if !slices.Contains(undefined, name) {
+				undefined = append(undefined, name)
+			}
+			continue
+		}
+
+		switch style {
+		case placeholderQuestion:
+			args = append(args, value)
+			out.WriteByte('?')
+		case placeholderDollar:
+			position, seen := positions[name]
+			if !seen {
+				args = append(args, value)
+				position = len(args)
+				positions[name] = position
+			}
+			out.WriteString("$" + strconv.Itoa(position))
+		}
+	}
+
+	if len(undefined) > 0 {
+		return "", nil, fmt.Errorf("%w: %s", ErrUndefinedParameter, strings.Join(undefined, ", "))
+	}
+
+	return out.String(), args, nil
+}
+
+// QueryParamNames returns the distinct named parameters (":name") referenced by a query, in order of appearance.
+func QueryParamNames(query string) []string {
+	var names []string
+	for i := 0; i < len(query); {
+		if end := skipQuoted(query, i, false); end > i {
+			i = end
+			continue
+		}
+
+		name, end := parseParamName(query, i)
+		if name == "" {
+			i++
+			continue
+		}
+		i = end
+
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// NormalizeQuery returns the query with surrounding whitespace removed and every other run of whitespace collapsed into
+// a single space, so that queries differing only in layout compare equal. String literals, quoted identifiers, comments
+// and dollar-quoted strings are kept as written.
+func NormalizeQuery(query string) string {
+	var out strings.Builder
+	out.Grow(len(query))
+
+	query = strings.TrimSpace(query)
+	for i := 0; i < len(query); {
+		if end := skipQuoted(query, i, false); end > i {
+			out.WriteString(query[i:end])
+			i = end
+			continue
+		}
+
+		if !isSpace(query[i]) {
+			out.WriteByte(query[i])
+			i++
+			continue
+		}
+		for i < len(query) && isSpace(query[i]) {
+			i++
+		}
+		out.WriteByte(' ')
+	}
+
+	return out.String()
+}
+
+// parseParamName returns the parameter name and the index after it if a named parameter starts at i. A parameter is a
+// colon followed by an identifier, not preceded or followed by another colon (to leave "::" casts alone), and not
+// preceded by an identifier character or closing bracket (to leave array slices like "arr[lo:hi]" alone).
+func parseParamName(query string, i int) (string, int) {
+	if query[i] != ':' || i+1 >= len(query) || !isIdentStart(query[i+1]) {
+		return "", i
+	}
+	if i > 0 && (query[i-1] == ':' || query[i-1] == ']' || query[i-1] == ')' || isIdentPart(query[i-1])) {
+		return "", i
+	}
+
+	end := i + 2
+	for end < len(query) && isIdentPart(query[end]) {
+		end++
+	}
+
+	return query[i+1 : end], end
+}
+
+// skipQuoted returns the index after the quoted section, comment or dollar-quoted string starting at i, or i if none
+// starts there. Unterminated sections extend to the end of the query.
+func skipQuoted(query string, i int, backslashEscapes bool) int {
+	switch {
+	case query[i] == '\'' || query[i] == '"' || query[i] == '`':
+		quote := query[i]
+		for j := i + 1; j < len(query); j++ {
+			switch {
+			case backslashEscapes && query[j] == '\\':
+				j++ // Skip the escaped character.
+			case query[j] == quote && j+1 < len(query) && query[j+1] == quote:
+				j++ // Doubled quote is an escaped quote.
+			case query[j] == quote:
+				return j + 1
+			}
+		}
+		return len(query)
+
+	case strings.HasPrefix(query[i:], "--"):
+		if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+			return i + end + 1
+		}
+		return len(query)
+
+	case strings.HasPrefix(query[i:], "/*"):
+		if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+			return i + 2 + end + 2
+		}
+		return len(query)
+
+	case query[i] == '$':
+		// Dollar-quoted string: $tag$ ... $tag$ where tag is empty or an identifier.
+		tagEnd := i + 1
+		for tagEnd < len(query) && isIdentPart(query[tagEnd]) {
+			tagEnd++
+		}
+		if tagEnd >= len(query) || query[tagEnd] != '$' || (tagEnd > i+1 && !isIdentStart(query[i+1])) {
+			return i // Positional parameter like $1 or a plain dollar sign.
+		}
+		tag := query[i : tagEnd+1]
+		if end := strings.Index(query[tagEnd+1:], tag); end >= 0 {
+			return tagEnd + 1 + end + len(tag)
+		}
+		return len(query)
+
+	default:
+		return i
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,131 @@
+package datasource_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+func TestBindParams(t *testing.T) {
+	t.Parallel()
+
+	params := map[string]any{"region": "NORTH", "month": 10}
+
+	tests := []struct {
+		name             string
+		query            string
+		style            datasource.PlaceholderStyle
+		backslashEscapes bool
+		wantQuery        string
+		wantArgs         []any
+	}{
+		{
+			name:      "dollar placeholders reuse positions",
+			query:     "SELECT * FROM s WHERE region = :region AND month = :month OR backup = :region",
+			style:     datasource.PlaceholderDollar,
+			wantQuery: "SELECT * FROM s WHERE region = $1 AND month = $2 OR backup = $1",
+			wantArgs:  []any{"NORTH", 10},
+		},
+		{
+			name:      "question placeholders bind per occurrence",
+			query:     "SELECT * FROM s WHERE region = :region AND month = :month OR backup = :region",
+			style:     datasource.PlaceholderQuestion,
+			wantQuery: "SELECT * FROM s WHERE region = ? AND month = ? OR backup = ?",
+			wantArgs:  []any{"NORTH", 10, "NORTH"},
+		},
+		{
+			name:      "casts, literals and comments are left alone",
+			query:     "SELECT ':region', \":region\", x::text -- :region\nFROM s /* :month */ WHERE m = :month",
+			style:     datasource.PlaceholderDollar,
+			wantQuery: "SELECT ':region', \":region\", x::text -- :region\nFROM s /* :month */ WHERE m = $1",
+			wantArgs:  []any{10},
+		},
+		{
+			name:      "dollar-quoted strings are left alone",
+			query:     "SELECT $body$ :region $body$, $$:month$$ WHERE r = :region",
+			style:     datasource.PlaceholderDollar,
+			wantQuery: "SELECT $body$ :region $body$, $$:month$$ WHERE r = $1",
+			wantArgs:  []any{"NORTH"},
+		},
+		{
+			name:             "backslash escapes in literals",
+			query:            `SELECT 'it\'s :region' WHERE r = :region`,
+			style:            datasource.PlaceholderQuestion,
+			backslashEscapes: true,
+			wantQuery:        `SELECT 'it\'s :region' WHERE r = ?`,
+			wantArgs:         []any{"NORTH"},
+		},
+		{
+			name:      "no parameters",
+			query:     "SELECT 1",
+			style:     datasource.PlaceholderDollar,
+			wantQuery: "SELECT 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			query, args, err := datasource.BindParams(tt.query, params, tt.style, tt.backslashEscapes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantQuery, query)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestBindParams_Undefined(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := datasource.BindParams(
+		"SELECT :a, :b, :a",
+		map[string]any{"b": 1},
+		datasource.PlaceholderDollar,
+		false,
+	)
+	require.ErrorIs(t, err, datasource.ErrUndefinedParameter)
+	assert.ErrorContains(t, err, ": a")
+}
+
+func TestQueryParamNames(t *testing.T) {
+	t.Parallel()
+
+	names := datasource.QueryParamNames("SELECT :region, x::date, ':month' FROM t WHERE d >= :from AND r = :region")
+	assert.Equal(t, []string{"region", "from"}, names)
+
+	// Array slices aren't parameters, unlike a parameter used as a slice bound.
+	names = datasource.QueryParamNames("SELECT arr[lo:hi], arr[1:n], (arr)[2:3], f(x)[1:k], arr[:upper] FROM t")
+	assert.Equal(t, []string{"upper"}, names)
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "whitespace is collapsed",
+			query: "\n  SELECT total\n\tFROM   totals\r\nWHERE year = :year  \n",
+			want:  "SELECT total FROM totals WHERE year = :year",
+		},
+		{
+			name:  "literals and comments are kept",
+			query: "SELECT 'a  b',  \"x  y\"  -- note  this\n  FROM s /*  c  */",
+			want:  "SELECT 'a  b', \"x  y\" -- note  this\n FROM s /*  c  */",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, datasource.NormalizeQuery(tt.query))
+		})
+	}
+}
@@ -5,18 +5,32 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nikoksr/assert-go"
+	"github.com/shopspring/decimal"
 )
 
-// Compile-time check to ensure PostgresDataSource implements the DataSource interface.
-var _ DataSource = (*PostgresDataSource)(nil)
+// Compile-time check to ensure PostgresDataSource implements the DataSource, Describer, Snapshotter and Pinger
+// interfaces.
+var (
+	_ DataSource  = (*PostgresDataSource)(nil)
+	_ Describer   = (*PostgresDataSource)(nil)
+	_ Snapshotter = (*PostgresDataSource)(nil)
+	_ Pinger      = (*PostgresDataSource)(nil)
+)
+
+// pgQuerier is what queries are executed through: the connection pool, or a transaction of a snapshot.
+type pgQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
 
 type PostgresDataSource struct {
 	pool   *pgxpool.Pool
@@ -46,6 +60,11 @@ func NewPostgresDataSource(ctx context.Context, cfg Config, logger *slog.Logger)
 		slog.String("database", config.ConnConfig.Database),
 	)
 
+	// Bounds every statement on the server, including those of snapshots.
+	if cfg.StatementTimeout > 0 {
+		config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
 	logger.Debug("Creating database connection pool...")
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
@@ -68,26 +87,34 @@ func NewPostgresDataSource(ctx context.Context, cfg Config, logger *slog.Logger)
 	}, nil
 }
 
-func (p *PostgresDataSource) FetchData(ctx context.Context, query string) (map[string]any, error) {
+func (p *PostgresDataSource) FetchData(
+	ctx context.Context,
+	query string,
+	params map[string]any,
+) (map[string]any, error) {
 	assert.Assert(ctx != nil, "context must not be nil")
 	assert.Assert(p.pool != nil, "database connection pool is nil")
 
-	if p.closed.Load() {
-		p.logger.Warn("Attempted to fetch data on a closed data source")
-		return nil, ErrDataSourceClosed
-	}
-
-	trimmedQuery := strings.TrimSpace(query)
-	if trimmedQuery == "" {
-		return nil, errors.New("query must not be empty")
-	}
+	var record map[string]any
+	err := p.readOnly(ctx, func(tx pgx.Tx) error {
+		var err error
+		record, err = p.fetchData(ctx, tx, query, params)
+		return err
+	})
+	return record, err
+}
 
-	p.logger.Debug("Executing query", slog.String("sql", trimmedQuery))
-	rows, err := p.pool.Query(ctx, trimmedQuery)
+func (p *PostgresDataSource) fetchData(
+	ctx context.Context,
+	querier pgQuerier,
+	query string,
+	params map[string]any,
+) (map[string]any, error) {
+	rows, trimmedQuery, err := p.query(ctx, querier, query, params)
 	if err != nil {
-		p.logger.Error("Failed to execute query", slog.String("sql", trimmedQuery), slog.String("error", err.Error()))
-		return nil, fmt.Errorf("execute query: %w", err)
+		return nil, err
 	}
+	zonedColumns := timestamptzColumns(rows.FieldDescriptions())
 
 	resultMap, err := pgx.CollectOneRow(rows, pgx.RowToMap)
 	if err != nil {
@@ -110,23 +137,232 @@ func (p *PostgresDataSource) FetchData(ctx context.Context, query string) (map[s
 
 	p.logger.Debug("Query returned one row successfully", slog.String("sql", trimmedQuery))
 
-	// Post-process the map to convert specific pgx types into more standard Go types for easier template consumption.
+	return p.convertRow(resultMap, zonedColumns, conversionOptionsFrom(ctx)), nil
+}
+
+func (p *PostgresDataSource) FetchRows(
+	ctx context.Context,
+	query string,
+	params map[string]any,
+) ([]map[string]any, error) {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(p.pool != nil, "database connection pool is nil")
+
+	var records []map[string]any
+	err := p.readOnly(ctx, func(tx pgx.Tx) error {
+		var err error
+		records, err = p.fetchRows(ctx, tx, query, params)
+		return err
+	})
+	return records, err
+}
+
+// readOnly runs fn in a READ ONLY transaction, so that report queries can't modify the database, whatever they
+// contain.
+func (p *PostgresDataSource) readOnly(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	if p.closed.Load() {
+		p.logger.Warn("Attempted to query a closed data source")
+		return ErrDataSourceClosed
+	}
+
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		p.logger.Error("Failed to begin read-only transaction", slog.String("error", err.Error()))
+		return fmt.Errorf("begin read-only transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // Nothing to commit; a no-op once the transaction ended.
+
+	return fn(tx)
+}
+
+func (p *PostgresDataSource) fetchRows(
+	ctx context.Context,
+	querier pgQuerier,
+	query string,
+	params map[string]any,
+) ([]map[string]any, error) {
+	rows, trimmedQuery, err := p.query(ctx, querier, query, params)
+	if err != nil {
+		return nil, err
+	}
+	zonedColumns := timestamptzColumns(rows.FieldDescriptions())
+
+	resultMaps, err := pgx.CollectRows(rows, pgx.RowToMap)
+	if err != nil {
+		p.logger.Error(
+			"Failed to collect rows data",
+			slog.String("sql", trimmedQuery),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	p.logger.Debug(
+		"Query returned rows successfully",
+		slog.String("sql", trimmedQuery),
+		slog.Int("row_count", len(resultMaps)),
+	)
+
+	opts := conversionOptionsFrom(ctx)
+	processedRows := make([]map[string]any, 0, len(resultMaps))
+	for _, resultMap := range resultMaps {
+		processedRows = append(processedRows, p.convertRow(resultMap, zonedColumns, opts))
+	}
+
+	return processedRows, nil
+}
+
+// query validates the data source state and the query, binds the named parameters and executes it through querier. The
+// caller owns the returned rows.
+func (p *PostgresDataSource) query(
+	ctx context.Context,
+	querier pgQuerier,
+	query string,
+	params map[string]any,
+) (pgx.Rows, string, error) {
+	if p.closed.Load() {
+		p.logger.Warn("Attempted to query a closed data source")
+		return nil, "", ErrDataSourceClosed
+	}
+
+	trimmedQuery := strings.TrimSpace(query)
+	if trimmedQuery == "" {
+		return nil, "", errors.New("query must not be empty")
+	}
+	if err := checkSingleStatement(trimmedQuery, false); err != nil {
+		return nil, "", err
+	}
+
+	boundQuery, args, err := bindParams(trimmedQuery, params, placeholderDollar, false)
+	if err != nil {
+		p.logger.Error("Failed to bind query parameters", slog.String("sql", trimmedQuery), slog.String("error", err.Error()))
+		return nil, "", fmt.Errorf("bind query parameters: %w", err)
+	}
+
+	p.logger.Debug("Executing query", slog.String("sql", boundQuery), slog.Int("arg_count", len(args)))
+	rows, err := querier.Query(ctx, boundQuery, args...)
+	if err != nil {
+		p.logger.Error("Failed to execute query", slog.String("sql", boundQuery), slog.String("error", err.Error()))
+		return nil, "", fmt.Errorf("execute query: %w", err)
+	}
+
+	return rows, trimmedQuery, nil
+}
+
+// DescribeQuery prepares the query as the unnamed statement and returns the result columns from its description. The
+// query is never executed.
+func (p *PostgresDataSource) DescribeQuery(ctx context.Context, query string, params map[string]any) ([]Column, error) {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(p.pool != nil, "database connection pool is nil")
+
+	if p.closed.Load() {
+		p.logger.Warn("Attempted to describe a query on a closed data source")
+		return nil, ErrDataSourceClosed
+	}
+
+	trimmedQuery := strings.TrimSpace(query)
+	if trimmedQuery == "" {
+		return nil, errors.New("query must not be empty")
+	}
+
+	boundQuery, _, err := bindParams(trimmedQuery, params, placeholderDollar, false)
+	if err != nil {
+		return nil, fmt.Errorf("bind query parameters: %w", err)
+	}
+
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	p.logger.Debug("Describing query", slog.String("sql", boundQuery))
+	description, err := conn.Conn().PgConn().Prepare(ctx, "", boundQuery, nil)
+	if err != nil {
+		p.logger.Error("Failed to describe query", slog.String("sql", boundQuery), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("describe query: %w", err)
+	}
+
+	typeMap := conn.Conn().TypeMap()
+	columns := make([]Column, 0, len(description.Fields))
+	for _, field := range description.Fields {
+		column := Column{Name: field.Name}
+		if dataType, ok := typeMap.TypeForOID(field.DataTypeOID); ok {
+			column.DatabaseType = dataType.Name
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+// convertRow post-processes a row map to convert specific pgx types into more standard Go types for easier template
+// consumption. Values of the zoned columns, which hold timestamps with a time zone, are converted into the options'
+// location.
+func (p *PostgresDataSource) convertRow(
+	resultMap map[string]any,
+	zonedColumns map[string]bool,
+	opts conversionOptions,
+) map[string]any {
 	processedMap := make(map[string]any, len(resultMap))
 	for key, value := range resultMap {
-		processedMap[key] = p.convertPgValue(key, value)
+		converted := p.convertPgValue(key, value, opts.exactDecimals)
+		if zonedColumns[key] {
+			converted = inLocation(converted, opts.location)
+		}
+		processedMap[key] = converted
 	}
 
-	return processedMap, nil
+	return processedMap
+}
+
+// timestamptzColumns returns the names of the result columns of type TIMESTAMPTZ or TIMESTAMPTZ[].
+func timestamptzColumns(fields []pgconn.FieldDescription) map[string]bool {
+	columns := make(map[string]bool)
+	for _, field := range fields {
+		if field.DataTypeOID == pgtype.TimestamptzOID || field.DataTypeOID == pgtype.TimestamptzArrayOID {
+			columns[field.Name] = true
+		}
+	}
+	return columns
+}
+
+// inLocation converts a converted timestamp, or the timestamps of an array, into location.
+func inLocation(value any, location *time.Location) any {
+	switch v := value.(type) {
+	case time.Time:
+		return v.In(location)
+	case []any:
+		for i, element := range v {
+			v[i] = inLocation(element, location)
+		}
+		return v
+	default:
+		return value
+	}
 }
 
-func (p *PostgresDataSource) convertPgValue(key string, value any) any {
+func (p *PostgresDataSource) convertPgValue(key string, value any, exactDecimals bool) any {
 	logger := p.logger.With(slog.String("key", key))
 
 	switch v := value.(type) {
 	case pgtype.Numeric:
+		// NaN and infinity have no exact decimal representation, but float64 equivalents.
+		isFinite := v.Valid && !v.NaN && v.InfinityModifier == pgtype.Finite && v.Int != nil
+		if exactDecimals && isFinite {
+			logger.Debug("Converting pgtype.Numeric to decimal.Decimal", slog.Any("original_value", v))
+			return decimal.NewFromBigInt(v.Int, v.Exp)
+		}
+
 		// Attempt to convert pgtype.Numeric to float64. This might lose precision for very large numbers.
 		floatVal, err := v.Float64Value()
 		if err != nil || !floatVal.Valid {
+			if isFinite {
+				// Out of float64 range; keep the exact value instead of blanking the cell.
+				logger.Warn("pgtype.Numeric exceeds float64 range, keeping it as decimal.Decimal",
+					slog.Any("original_value", v))
+				return decimal.NewFromBigInt(v.Int, v.Exp)
+			}
 			logger.Warn("Failed to convert pgtype.Numeric to valid float64", slog.Any("original_value", v))
 			return nil
 		}
@@ -156,7 +392,19 @@ func (p *PostgresDataSource) convertPgValue(key string, value any) any {
 			logger,
 		)
 
-	// TODO: ?; JSONB -> map[string]any or string, arrays -> slices
+	case pgtype.InfinityModifier:
+		// Infinite dates and timestamps are decoded into their modifier only.
+		return convertPGInfinity(v)
+
+	case []any:
+		// Arrays are decoded into slices; their elements are converted like single values.
+		elements := make([]any, len(v))
+		for i, element := range v {
+			elements[i] = p.convertPgValue(key, element, exactDecimals)
+		}
+		return elements
+
+	// JSON and JSONB are decoded into map[string]any, []any and the JSON scalar types, which need no conversion.
 
 	default:
 		return value // Return other types as-is.
@@ -176,16 +424,37 @@ func convertPGTime(
 
 	logger.Debug(fmt.Sprintf("Converting %s to time.Time", pgTypeName), slog.Any("original_value", originalValue))
 
+	if infModifier != pgtype.Finite {
+		return convertPGInfinity(infModifier)
+	}
+	return timeValue
+}
+
+// convertPGInfinity converts the modifier of an infinite date or timestamp into an InfiniteTime.
+func convertPGInfinity(infModifier pgtype.InfinityModifier) any {
 	switch infModifier {
 	case pgtype.Infinity:
-		return "infinity"
+		return InfiniteTime{}
 	case pgtype.NegativeInfinity:
-		return "-infinity"
+		return InfiniteTime{Negative: true}
 	case pgtype.Finite:
 		fallthrough
 	default:
-		return timeValue
+		return infModifier
+	}
+}
+
+func (p *PostgresDataSource) Ping(ctx context.Context) error {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(p.pool != nil, "database connection pool is nil")
+
+	if p.closed.Load() {
+		return ErrDataSourceClosed
+	}
+	if err := p.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("ping database: %w", err)
 	}
+	return nil
 }
 
 func (p *PostgresDataSource) Close(ctx context.Context) error {
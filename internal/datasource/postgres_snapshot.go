@@ -0,0 +1,249 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nikoksr/assert-go"
+)
+
+// Compile-time check to ensure postgresSnapshot implements the DataSource interface.
+var _ DataSource = (*postgresSnapshot)(nil)
+
+// snapshotTxOptions are the options of every transaction of a snapshot.
+var snapshotTxOptions = pgx.TxOptions{ //nolint:gochecknoglobals // Read-only options.
+	IsoLevel:   pgx.RepeatableRead,
+	AccessMode: pgx.ReadOnly,
+}
+
+// postgresSnapshot runs queries in REPEATABLE READ, READ ONLY transactions that all share the snapshot exported by the
+// first one. Each transaction runs one query at a time; further transactions, on further pooled connections, are only
+// begun while all others are busy, up to the pool's connection limit. The first transaction has to stay open for as
+// long as the snapshot is in use, hence all of them are only ended when the snapshot is closed. Since snapshots of
+// concurrent reports share the pool, waiting for a connection to begin another transaction is given up as soon as one
+// of the snapshot's own transactions becomes idle; otherwise two snapshots could wait for each other's connections.
+type postgresSnapshot struct {
+	source     *PostgresDataSource
+	snapshotID string
+	maxTxs     int
+
+	idle   chan pgx.Tx // Transactions not running a query.
+	mu     sync.Mutex
+	txs    []pgx.Tx       // All transactions, for Close.
+	opened int            // Transactions begun or being begun.
+	begins sync.WaitGroup // Transactions being begun, including abandoned ones; awaited by Close.
+	closed atomic.Bool
+	logger *slog.Logger
+}
+
+// Snapshot begins a REPEATABLE READ, READ ONLY transaction and exports its snapshot, so that all queries of the
+// returned data source see the database as of this moment, including those running concurrently on other connections.
+func (p *PostgresDataSource) Snapshot(ctx context.Context) (DataSource, error) {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(p.pool != nil, "database connection pool is nil")
+
+	if p.closed.Load() {
+		return nil, ErrDataSourceClosed
+	}
+
+	tx, err := p.pool.BeginTx(ctx, snapshotTxOptions)
+	if err != nil {
+		return nil, fmt.Errorf("begin snapshot transaction: %w", err)
+	}
+
+	var snapshotID string
+	if err := tx.QueryRow(ctx, "SELECT pg_export_snapshot()").Scan(&snapshotID); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, fmt.Errorf("export snapshot: %w", err)
+	}
+
+	maxTxs := int(p.pool.Config().MaxConns)
+	s := &postgresSnapshot{
+		source:     p,
+		snapshotID: snapshotID,
+		maxTxs:     maxTxs,
+		idle:       make(chan pgx.Tx, maxTxs),
+		txs:        []pgx.Tx{tx},
+		opened:     1,
+		logger:     p.logger.With(slog.String("snapshot", snapshotID)),
+	}
+	s.idle <- tx
+
+	s.logger.Info("Snapshot taken, all queries see the database as of now")
+	return s, nil
+}
+
+func (s *postgresSnapshot) FetchData(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
+	assert.Assert(ctx != nil, "context must not be nil")
+
+	var record map[string]any
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		record, err = s.source.fetchData(ctx, tx, query, params)
+		return err
+	})
+	return record, err
+}
+
+func (s *postgresSnapshot) FetchRows(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	assert.Assert(ctx != nil, "context must not be nil")
+
+	var records []map[string]any
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		records, err = s.source.fetchRows(ctx, tx, query, params)
+		return err
+	})
+	return records, err
+}
+
+// Close ends the transactions of the snapshot. Queries must not be running anymore.
+func (s *postgresSnapshot) Close(ctx context.Context) error {
+	assert.Assert(ctx != nil, "context must not be nil")
+
+	if !s.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	s.begins.Wait() // Abandoned begins end promptly, their contexts are cancelled.
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for _, tx := range s.txs {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			errs = append(errs, err)
+		}
+	}
+	s.logger.Debug("Snapshot released", slog.Int("transaction_count", len(s.txs)))
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("end snapshot transactions: %w", err)
+	}
+	return nil
+}
+
+// withTx runs fn with a transaction of the snapshot, within a savepoint: a failing query aborts the transaction it ran
+// in, which would fail all following queries as well.
+func (s *postgresSnapshot) withTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	if s.closed.Load() {
+		return ErrDataSourceClosed
+	}
+
+	tx, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { s.idle <- tx }()
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("create savepoint: %w", err)
+	}
+	if err := fn(savepoint); err != nil {
+		_ = savepoint.Rollback(ctx) // Rolling back a savepoint of a read-only transaction loses nothing.
+		return err
+	}
+	if err := savepoint.Commit(ctx); err != nil {
+		return fmt.Errorf("release savepoint: %w", err)
+	}
+
+	return nil
+}
+
+// beginResult is the outcome of beginning another transaction of a snapshot.
+type beginResult struct {
+	tx  pgx.Tx
+	err error
+}
+
+// acquire returns an idle transaction, begins another one if all are busy and the connection limit allows it, or waits
+// for one to become idle. While another transaction is being begun, a transaction becoming idle is taken instead and
+// the begin is abandoned.
+func (s *postgresSnapshot) acquire(ctx context.Context) (pgx.Tx, error) {
+	select {
+	case tx := <-s.idle:
+		return tx, nil
+	default:
+	}
+
+	s.mu.Lock()
+	if s.opened >= s.maxTxs {
+		s.mu.Unlock()
+		select {
+		case tx := <-s.idle:
+			return tx, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	s.opened++
+	s.mu.Unlock()
+
+	beginCtx, cancelBegin := context.WithCancel(ctx)
+	result := make(chan beginResult, 1)
+	s.begins.Add(1)
+	go func() {
+		defer s.begins.Done()
+		tx, err := s.begin(beginCtx)
+		result <- beginResult{tx: tx, err: err}
+	}()
+
+	select {
+	case r := <-result:
+		cancelBegin()
+		if r.err != nil {
+			s.abandonBegin()
+			return nil, r.err
+		}
+		return r.tx, nil
+	case tx := <-s.idle:
+		cancelBegin()
+		s.begins.Add(1)
+		go func() {
+			defer s.begins.Done()
+			// The begin may have succeeded before it noticed the cancellation; its transaction is idle then.
+			if r := <-result; r.err == nil {
+				s.idle <- r.tx
+				return
+			}
+			s.abandonBegin()
+		}()
+		return tx, nil
+	}
+}
+
+// abandonBegin gives back the slot of a transaction that failed to begin.
+func (s *postgresSnapshot) abandonBegin() {
+	s.mu.Lock()
+	s.opened--
+	s.mu.Unlock()
+}
+
+// begin begins another transaction on a pooled connection and imports the snapshot into it.
+func (s *postgresSnapshot) begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := s.source.pool.BeginTx(ctx, snapshotTxOptions)
+	if err != nil {
+		return nil, fmt.Errorf("begin snapshot transaction: %w", err)
+	}
+
+	// SET TRANSACTION doesn't take parameters; the ID is generated by the server and quoted nonetheless.
+	quotedID := "'" + strings.ReplaceAll(s.snapshotID, "'", "''") + "'"
+	if _, err := tx.Exec(ctx, "SET TRANSACTION SNAPSHOT "+quotedID); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, fmt.Errorf("import snapshot: %w", err)
+	}
+
+	s.mu.Lock()
+	s.txs = append(s.txs, tx)
+	s.mu.Unlock()
+
+	s.logger.Debug("Began another transaction on the snapshot")
+	return tx, nil
+}
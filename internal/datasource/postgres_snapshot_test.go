@@ -0,0 +1,86 @@
+package datasource_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+func TestPostgresSnapshot_SharedPool(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping PostgreSQL test in short mode")
+	}
+
+	ctx := t.Context()
+	pgContainer, err := postgres.Run(ctx, "postgres:17-alpine",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("user"),
+		postgres.WithPassword("password"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Minute),
+		),
+	)
+	require.NoError(t, err, "Failed to start PostgreSQL container")
+	t.Cleanup(func() {
+		if err := pgContainer.Terminate(context.Background()); err != nil {
+			t.Logf("Warning: failed to terminate test container: %v", err)
+		}
+	})
+
+	host, err := pgContainer.Host(ctx)
+	require.NoError(t, err)
+	port, err := pgContainer.MappedPort(ctx, "5432/tcp")
+	require.NoError(t, err)
+	// Two connections: the first transaction of each snapshot takes one, so the pool is exhausted from the start.
+	dsn := fmt.Sprintf("postgres://user:password@%s/testdb?sslmode=disable&pool_max_conns=2",
+		net.JoinHostPort(host, port.Port()))
+
+	logger := slog.New(slog.DiscardHandler)
+	source, err := datasource.NewPostgresDataSource(ctx, datasource.Config{DSN: dsn}, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = source.Close(context.Background()) })
+
+	// Two reports run their queries concurrently, each on its own snapshot. Every query finding its snapshot's only
+	// transaction busy tries to begin another one on the exhausted pool; it must take the transaction once it is idle
+	// instead of waiting for a connection held by the other snapshot.
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 2 {
+		snapshot, err := source.Snapshot(ctx)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = snapshot.Close(context.Background()) })
+
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := snapshot.FetchData(queryCtx, "SELECT 1 AS n FROM pg_sleep(0.1)", nil)
+				errs <- err
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, queryCtx.Err(), "queries must not wait for connections held by the other snapshot")
+}
@@ -0,0 +1,201 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/nikoksr/assert-go"
+)
+
+// DefaultSourceName is the name of a data source configured without an explicit name. References without a source
+// prefix are routed to it.
+const DefaultSourceName = "default"
+
+// ErrUnknownDataSource indicates that a reference named a data source that is not configured.
+var ErrUnknownDataSource = errors.New("unknown data source")
+
+// sourceNameRegex validates data source names as used in "--dsn name=..." and "name:path/to/query.sql".
+var sourceNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// IsValidSourceName reports whether name can be used as a data source name.
+func IsValidSourceName(name string) bool {
+	return sourceNameRegex.MatchString(name)
+}
+
+// ParseNamedDSN parses a data source specification of the form "name=scheme://..." or a plain DSN. A plain DSN
+// (including PostgreSQL keyword/value strings like "host=localhost user=excalibur") yields an unnamed config.
+func ParseNamedDSN(spec string) Config {
+	spec = strings.TrimSpace(spec)
+
+	name, dsn, found := strings.Cut(spec, "=")
+	if !found || !IsValidSourceName(name) {
+		return Config{DSN: spec}
+	}
+
+	// Only treat the prefix as a name if the remainder is a URL-like DSN; otherwise it's a keyword/value DSN.
+	if scheme, _ := splitScheme(dsn); scheme == "" {
+		return Config{DSN: spec}
+	}
+
+	return Config{Name: name, DSN: strings.TrimSpace(dsn)}
+}
+
+// Registry holds the named data sources of a report and routes lookups to them.
+type Registry struct {
+	sources map[string]DataSource
+	names   []string // Sorted, for deterministic iteration.
+	logger  *slog.Logger
+}
+
+// NewRegistry creates a registry from already opened data sources keyed by name.
+func NewRegistry(sources map[string]DataSource, logger *slog.Logger) *Registry {
+	assert.Assert(len(sources) > 0, "at least one data source must be registered")
+	assert.Assert(logger != nil, "logger must not be nil")
+
+	names := make([]string, 0, len(sources))
+	for name, source := range sources {
+		assert.Assert(source != nil, "data source must not be nil")
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return &Registry{
+		sources: sources,
+		names:   names,
+		logger:  logger.With(slog.String("component", "DataSourceRegistry")),
+	}
+}
+
+// OpenRegistry opens a data source for every config. Unnamed configs are registered as DefaultSourceName. If any data
+// source fails to open, the already opened ones are closed again.
+func OpenRegistry(ctx context.Context, cfgs []Config, logger *slog.Logger) (*Registry, error) {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(len(cfgs) > 0, "at least one data source config must be provided")
+	assert.Assert(logger != nil, "logger must not be nil")
+
+	sources := make(map[string]DataSource, len(cfgs))
+	for _, cfg := range cfgs {
+		name := cfg.SourceName()
+		if _, exists := sources[name]; exists {
+			_ = closeAll(ctx, sources, logger) // Best-effort cleanup, failures are logged.
+			return nil, fmt.Errorf("data source %q configured more than once", name)
+		}
+
+		logger.Info("Opening data source", slog.String("source", name))
+		source, err := Open(ctx, cfg, logger.With(slog.String("source", name)))
+		if err != nil {
+			_ = closeAll(ctx, sources, logger) // Best-effort cleanup, failures are logged.
+			return nil, fmt.Errorf("open data source %q: %w", name, err)
+		}
+		sources[name] = source
+	}
+
+	return NewRegistry(sources, logger), nil
+}
+
+// Lookup returns the data source with the given name. An empty name selects DefaultSourceName, or the only registered
+// data source if there is exactly one.
+func (r *Registry) Lookup(name string) (DataSource, error) {
+	if name == "" {
+		if len(r.sources) == 1 {
+			return r.sources[r.names[0]], nil
+		}
+		name = DefaultSourceName
+	}
+
+	source, ok := r.sources[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q (configured: %s)", ErrUnknownDataSource, name, strings.Join(r.names, ", "))
+	}
+
+	return source, nil
+}
+
+// Names returns the sorted names of all registered data sources.
+func (r *Registry) Names() []string {
+	return slices.Clone(r.names)
+}
+
+// Snapshot returns a registry of the same data sources whose queries all run against a consistent snapshot, taken now,
+// if the data source implements Snapshotter. Other data sources are registered as they are, and their queries see
+// whatever is committed when they run. Closing the returned registry releases the snapshots only.
+func (r *Registry) Snapshot(ctx context.Context) (*Registry, error) {
+	assert.Assert(ctx != nil, "context must not be nil")
+
+	sources := make(map[string]DataSource, len(r.sources))
+	snapshots := make(map[string]DataSource, len(r.sources))
+	for _, name := range r.names {
+		source := r.sources[name]
+		snapshotter, ok := source.(Snapshotter)
+		if !ok {
+			r.logger.Warn(
+				"Data source doesn't support snapshots, its queries may see changes made while the report runs",
+				slog.String("source", name),
+			)
+			sources[name] = unclosable{source}
+			continue
+		}
+
+		snapshot, err := snapshotter.Snapshot(ctx)
+		if err != nil {
+			_ = closeAll(ctx, snapshots, r.logger) // Best-effort cleanup, failures are logged.
+			return nil, fmt.Errorf("take snapshot of data source %q: %w", name, err)
+		}
+		sources[name] = snapshot
+		snapshots[name] = snapshot
+	}
+
+	return &Registry{sources: sources, names: r.names, logger: r.logger}, nil
+}
+
+// unclosable shields a data source registered with a snapshot registry from being closed along with the snapshots.
+type unclosable struct {
+	DataSource
+}
+
+func (unclosable) Close(context.Context) error { return nil }
+
+// Ping pings every registered data source that implements Pinger and returns the joined errors of those that can't be
+// reached. Other data sources are assumed to be reachable.
+func (r *Registry) Ping(ctx context.Context) error {
+	assert.Assert(ctx != nil, "context must not be nil")
+
+	var errs []error
+	for _, name := range r.names {
+		pinger, ok := r.sources[name].(Pinger)
+		if !ok {
+			continue
+		}
+		if err := pinger.Ping(ctx); err != nil {
+			r.logger.Warn("Data source unreachable", slog.String("source", name), slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("data source %q: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close closes all registered data sources and returns the joined errors of those that failed.
+func (r *Registry) Close(ctx context.Context) error {
+	assert.Assert(ctx != nil, "context must not be nil")
+
+	return closeAll(ctx, r.sources, r.logger)
+}
+
+func closeAll(ctx context.Context, sources map[string]DataSource, logger *slog.Logger) error {
+	var errs []error
+	for name, source := range sources {
+		logger.Debug("Closing data source", slog.String("source", name))
+		if err := source.Close(ctx); err != nil {
+			logger.Warn("Error closing data source", slog.String("source", name), slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("close data source %q: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,215 @@
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nikoksr/assert-go"
+)
+
+// valueConverter normalizes a scanned database/sql value into the Go types the report generator expects, based on the
+// column's name and uppercase database type name (e.g. "DECIMAL"), and the conversion options of the query.
+type valueConverter func(logger *slog.Logger, column, databaseType string, value any, opts conversionOptions) any
+
+// sqlDataSource implements the DataSource interface on top of database/sql. Driver specific data sources embed it and
+// provide a valueConverter that maps the driver's values to the types the PostgreSQL data source produces.
+type sqlDataSource struct {
+	db               *sql.DB
+	closed           atomic.Bool
+	logger           *slog.Logger
+	convert          valueConverter
+	backslashEscapes bool          // Whether string literals use backslash escapes, relevant for binding parameters.
+	statementTimeout time.Duration // Zero means no limit.
+}
+
+// openSQLDataSource pings the database behind db and initializes the embedded sqlDataSource of a driver specific data
+// source. The database is closed if it cannot be reached.
+func openSQLDataSource(
+	ctx context.Context,
+	target *sqlDataSource,
+	db *sql.DB,
+	convert valueConverter,
+	logger *slog.Logger,
+) error {
+	logger.Info("Pinging database...")
+	if err := db.PingContext(ctx); err != nil {
+		logger.Error("Failed to ping database", slog.String("error", err.Error()))
+		_ = db.Close() // Attempt cleanup
+		return fmt.Errorf("ping database: %w", err)
+	}
+
+	target.db = db
+	target.logger = logger
+	target.convert = convert
+
+	return nil
+}
+
+func (s *sqlDataSource) FetchData(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(s.db != nil, "database handle is nil")
+
+	results, trimmedQuery, err := s.query(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(results) {
+	case 0:
+		s.logger.Warn("Query returned no rows", slog.String("sql", trimmedQuery))
+		return nil, ErrQueryReturnedNoRows
+	case 1:
+		s.logger.Debug("Query returned one row successfully", slog.String("sql", trimmedQuery))
+		return results[0], nil
+	default:
+		s.logger.Warn("Query returned multiple rows, expected one", slog.String("sql", trimmedQuery))
+		return nil, fmt.Errorf("%w: got %d rows", ErrQueryReturnedMultipleRows, len(results))
+	}
+}
+
+func (s *sqlDataSource) FetchRows(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(s.db != nil, "database handle is nil")
+
+	results, trimmedQuery, err := s.query(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Debug(
+		"Query returned rows successfully",
+		slog.String("sql", trimmedQuery),
+		slog.Int("row_count", len(results)),
+	)
+	return results, nil
+}
+
+func (s *sqlDataSource) Ping(ctx context.Context) error {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(s.db != nil, "database handle is nil")
+
+	if s.closed.Load() {
+		return ErrDataSourceClosed
+	}
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping database: %w", err)
+	}
+	return nil
+}
+
+func (s *sqlDataSource) Close(ctx context.Context) error {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(s.db != nil, "database handle is nil")
+
+	if !s.closed.CompareAndSwap(false, true) {
+		s.logger.Debug("Close called on already closed data source.")
+		return nil
+	}
+
+	if err := s.db.Close(); err != nil {
+		s.logger.Error("Failed to close database", slog.String("error", err.Error()))
+		return fmt.Errorf("close database: %w", err)
+	}
+	s.logger.Info("Database closed.")
+
+	return nil
+}
+
+// query validates the data source state and the query, binds the named parameters and executes it in a read-only
+// transaction, so that report queries can't modify the database, whatever they contain. It returns all rows.
+func (s *sqlDataSource) query(
+	ctx context.Context,
+	query string,
+	params map[string]any,
+) ([]map[string]any, string, error) {
+	if s.closed.Load() {
+		s.logger.Warn("Attempted to query a closed data source")
+		return nil, "", ErrDataSourceClosed
+	}
+
+	trimmedQuery := strings.TrimSpace(query)
+	if trimmedQuery == "" {
+		return nil, "", errors.New("query must not be empty")
+	}
+	if err := checkSingleStatement(trimmedQuery, s.backslashEscapes); err != nil {
+		return nil, "", err
+	}
+
+	boundQuery, args, err := bindParams(trimmedQuery, params, placeholderQuestion, s.backslashEscapes)
+	if err != nil {
+		s.logger.Error("Failed to bind query parameters", slog.String("sql", trimmedQuery), slog.String("error", err.Error()))
+		return nil, "", fmt.Errorf("bind query parameters: %w", err)
+	}
+
+	if s.statementTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.statementTimeout)
+		defer cancel()
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		s.logger.Error("Failed to begin read-only transaction", slog.String("error", err.Error()))
+		return nil, "", fmt.Errorf("begin read-only transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Nothing to commit.
+
+	s.logger.Debug("Executing query", slog.String("sql", boundQuery), slog.Int("arg_count", len(args)))
+	rows, err := tx.QueryContext(ctx, boundQuery, args...)
+	if err != nil {
+		s.logger.Error("Failed to execute query", slog.String("sql", boundQuery), slog.String("error", err.Error()))
+		return nil, "", fmt.Errorf("execute query: %w", err)
+	}
+
+	results, err := s.collectRows(ctx, rows)
+	if err != nil {
+		s.logger.Error("Failed to collect rows", slog.String("sql", trimmedQuery), slog.String("error", err.Error()))
+		return nil, "", fmt.Errorf("collect rows: %w", err)
+	}
+
+	return results, trimmedQuery, nil
+}
+
+// collectRows scans all remaining rows into maps of column names to converted values and closes the rows. Values are
+// converted according to the options set on ctx.
+func (s *sqlDataSource) collectRows(ctx context.Context, rows *sql.Rows) ([]map[string]any, error) {
+	defer rows.Close()
+
+	opts := conversionOptionsFrom(ctx)
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("get column types: %w", err)
+	}
+
+	var results []map[string]any
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			databaseType := strings.ToUpper(column.DatabaseTypeName())
+			row[column.Name()] = s.convert(s.logger, column.Name(), databaseType, values[i], opts)
+		}
+		results = append(results, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return results, nil
+}
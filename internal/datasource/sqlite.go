@@ -0,0 +1,164 @@
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nikoksr/assert-go"
+	_ "modernc.org/sqlite" // Registers the pure-Go "sqlite" database/sql driver.
+)
+
+// Compile-time check to ensure SQLiteDataSource implements the DataSource and Pinger interfaces.
+var (
+	_ DataSource = (*SQLiteDataSource)(nil)
+	_ Pinger     = (*SQLiteDataSource)(nil)
+)
+
+// sqliteDriverName is the database/sql driver name registered by modernc.org/sqlite.
+const sqliteDriverName = "sqlite"
+
+// sqliteQueryOnly is the driver DSN parameter that prevents all changes to the database file.
+const sqliteQueryOnly = "_pragma=query_only(1)"
+
+// sqliteTimeLayouts are the text formats SQLite date and time functions produce, tried in order when a DATE, DATETIME
+// or TIMESTAMP column holds text the driver did not already parse.
+var sqliteTimeLayouts = []string{ //nolint:gochecknoglobals // Read-only lookup table.
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+type SQLiteDataSource struct {
+	sqlDataSource
+}
+
+func NewSQLiteDataSource(ctx context.Context, cfg Config, logger *slog.Logger) (*SQLiteDataSource, error) {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(cfg.DSN != "", "DSN must not be empty")
+	assert.Assert(logger != nil, "logger must not be nil")
+
+	logger = logger.With(slog.String("component", "SQLiteDataSource"))
+
+	logger.Info("Initializing SQLite data source...")
+	path := sqliteDatabasePath(cfg.DSN)
+	if path == "" {
+		logger.Error("DSN does not contain a database path")
+		return nil, errors.New("parse database path from DSN: path must not be empty")
+	}
+	logger.Debug("Parsed database path from DSN", slog.String("path", path))
+
+	db, err := sql.Open(sqliteDriverName, sqliteDriverDSN(cfg.DSN))
+	if err != nil {
+		logger.Error("Failed to open database", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("open sqlite database %q: %w", path, err)
+	}
+
+	source := &SQLiteDataSource{}
+	source.statementTimeout = cfg.StatementTimeout
+	if err := openSQLDataSource(ctx, &source.sqlDataSource, db, convertSQLiteValue, logger); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Database opened successfully.")
+	return source, nil
+}
+
+// convertSQLiteValue normalizes SQLite's dynamically typed values based on the declared column type, so they match the
+// Go types the PostgreSQL data source produces: numerics become float64, dates and timestamps time.Time and booleans
+// bool.
+// SQLite has neither exact numeric storage nor timestamps with a time zone, so the conversion options are ignored.
+func convertSQLiteValue(logger *slog.Logger, column, declaredType string, value any, _ conversionOptions) any {
+	if value == nil {
+		return nil
+	}
+
+	logger = logger.With(slog.String("key", column))
+
+	switch {
+	case isSQLiteNumericType(declaredType):
+		switch v := value.(type) {
+		case int64:
+			return float64(v)
+		case string:
+			floatVal, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				logger.Warn("Failed to convert SQLite numeric text to float64", slog.Any("original_value", v))
+				return nil
+			}
+			return floatVal
+		}
+
+	case isSQLiteTimeType(declaredType):
+		if v, ok := value.(string); ok {
+			for _, layout := range sqliteTimeLayouts {
+				if parsed, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+					logger.Debug("Converting SQLite time text to time.Time", slog.Any("original_value", v))
+					return parsed
+				}
+			}
+			logger.Warn("Failed to parse SQLite time text, keeping original value", slog.Any("original_value", v))
+			return v
+		}
+
+	case declaredType == "BOOLEAN" || declaredType == "BOOL":
+		if v, ok := value.(int64); ok {
+			return v != 0
+		}
+	}
+
+	// TEXT values may be returned as bytes; BLOB columns keep their raw bytes.
+	if v, ok := value.([]byte); ok && !strings.Contains(declaredType, "BLOB") {
+		return string(v)
+	}
+
+	return value
+}
+
+// isSQLiteNumericType reports whether a declared column type holds exact or approximate numbers with a fractional
+// part, following SQLite's type affinity rules for NUMERIC and REAL.
+func isSQLiteNumericType(declaredType string) bool {
+	for _, marker := range []string{"DEC", "NUM", "REAL", "FLOA", "DOUB"} {
+		if strings.Contains(declaredType, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSQLiteTimeType reports whether a declared column type holds dates or timestamps.
+func isSQLiteTimeType(declaredType string) bool {
+	return strings.Contains(declaredType, "DATE") || strings.Contains(declaredType, "TIME")
+}
+
+// sqliteDatabasePath extracts the database file path from a DSN like "sqlite:///path/to/file.db" (absolute) or
+// "sqlite://reports.db" (relative to the working directory).
+func sqliteDatabasePath(dsn string) string {
+	_, rest := splitScheme(dsn)
+	path, _, _ := strings.Cut(rest, "?")
+	if path == "" {
+		return ""
+	}
+	return filepath.FromSlash(path)
+}
+
+// sqliteDriverDSN converts a DSN into the filename form understood by the driver, keeping any query parameters (e.g.
+// "?_pragma=busy_timeout(5000)"). Connections are always opened with query_only, since SQLite ignores read-only
+// transactions.
+func sqliteDriverDSN(dsn string) string {
+	_, rest := splitScheme(dsn)
+	path, params, found := strings.Cut(rest, "?")
+	if !found || params == "" {
+		return filepath.FromSlash(path) + "?" + sqliteQueryOnly
+	}
+	return filepath.FromSlash(path) + "?" + params + "&" + sqliteQueryOnly
+}
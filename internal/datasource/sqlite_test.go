@@ -0,0 +1,186 @@
+package datasource_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+const sqliteSchema = `
+CREATE TABLE products (
+    product_id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    price DECIMAL(10, 2),
+    stock_count INT,
+    is_active BOOLEAN,
+    expiry_date DATE,
+    last_updated TIMESTAMP
+);
+
+INSERT INTO products (product_id, name, price, stock_count, is_active, expiry_date, last_updated) VALUES
+(1, 'Laptop Pro', 1200.50, 50, 1, '2025-12-31', '2024-01-15 10:30:00'),
+(2, 'Office Chair', 150, 30, 0, NULL, NULL);
+`
+
+func newTestSQLiteDataSource(t *testing.T) datasource.DataSource {
+	t.Helper()
+
+	dbPath := filepath.Join(t.TempDir(), "reports.db")
+	db, err := sql.Open("sqlite", dbPath)
+	require.NoError(t, err)
+	_, err = db.ExecContext(t.Context(), sqliteSchema)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	source, err := datasource.Open(t.Context(), datasource.Config{DSN: "sqlite://" + filepath.ToSlash(dbPath)}, logger)
+	require.NoError(t, err)
+	require.IsType(t, &datasource.SQLiteDataSource{}, source)
+	t.Cleanup(func() {
+		assert.NoError(t, source.Close(context.Background()))
+	})
+
+	return source
+}
+
+func TestSQLiteDataSource_FetchData(t *testing.T) {
+	t.Parallel()
+
+	source := newTestSQLiteDataSource(t)
+
+	row, err := source.FetchData(t.Context(), "SELECT * FROM products WHERE product_id = 1", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"product_id":   int64(1),
+		"name":         "Laptop Pro",
+		"price":        1200.50,
+		"stock_count":  int64(50),
+		"is_active":    true,
+		"expiry_date":  time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+		"last_updated": time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+	}, row)
+}
+
+func TestSQLiteDataSource_FetchDataNormalizesNullsAndIntegers(t *testing.T) {
+	t.Parallel()
+
+	source := newTestSQLiteDataSource(t)
+
+	row, err := source.FetchData(
+		t.Context(),
+		"SELECT price, is_active, expiry_date FROM products WHERE product_id = 2",
+		nil,
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{"price": 150.0, "is_active": false, "expiry_date": nil}, row)
+}
+
+func TestSQLiteDataSource_FetchDataRowCount(t *testing.T) {
+	t.Parallel()
+
+	source := newTestSQLiteDataSource(t)
+
+	_, err := source.FetchData(t.Context(), "SELECT * FROM products WHERE product_id = 999", nil)
+	require.ErrorIs(t, err, datasource.ErrQueryReturnedNoRows)
+
+	_, err = source.FetchData(t.Context(), "SELECT * FROM products", nil)
+	require.ErrorIs(t, err, datasource.ErrQueryReturnedMultipleRows)
+}
+
+func TestSQLiteDataSource_FetchRows(t *testing.T) {
+	t.Parallel()
+
+	source := newTestSQLiteDataSource(t)
+
+	rows, err := source.FetchRows(t.Context(), "SELECT name FROM products ORDER BY product_id", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"name": "Laptop Pro"}, {"name": "Office Chair"}}, rows)
+
+	rows, err = source.FetchRows(t.Context(), "SELECT name FROM products WHERE product_id = 999", nil)
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestSQLiteDataSource_FetchRowsWithParams(t *testing.T) {
+	t.Parallel()
+
+	source := newTestSQLiteDataSource(t)
+
+	rows, err := source.FetchRows(
+		t.Context(),
+		"SELECT name FROM products WHERE price >= :min_price OR name = :name ORDER BY product_id",
+		map[string]any{"min_price": 1000, "name": "Office Chair"},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"name": "Laptop Pro"}, {"name": "Office Chair"}}, rows)
+
+	_, err = source.FetchRows(t.Context(), "SELECT name FROM products WHERE name = :name", nil)
+	require.ErrorIs(t, err, datasource.ErrUndefinedParameter)
+}
+
+func TestSQLiteDataSource_Closed(t *testing.T) {
+	t.Parallel()
+
+	source := newTestSQLiteDataSource(t)
+	require.NoError(t, source.Close(t.Context()))
+
+	_, err := source.FetchData(t.Context(), "SELECT 1", nil)
+	require.ErrorIs(t, err, datasource.ErrDataSourceClosed)
+}
+
+func TestSQLiteDataSource_ReadOnly(t *testing.T) {
+	t.Parallel()
+
+	source := newTestSQLiteDataSource(t)
+
+	_, err := source.FetchRows(t.Context(), "DELETE FROM products RETURNING product_id", nil)
+	require.Error(t, err)
+
+	_, err = source.FetchRows(t.Context(), "SELECT 1; DELETE FROM products", nil)
+	require.ErrorIs(t, err, datasource.ErrMultipleStatements)
+
+	rows, err := source.FetchRows(t.Context(), "SELECT product_id FROM products", nil)
+	require.NoError(t, err)
+	assert.Len(t, rows, 2)
+}
+
+func TestSQLiteDataSource_StatementTimeout(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "reports.db")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	source, err := datasource.Open(t.Context(), datasource.Config{
+		DSN:              "sqlite://" + filepath.ToSlash(dbPath),
+		StatementTimeout: 50 * time.Millisecond,
+	}, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, source.Close(context.Background()))
+	})
+
+	// Counts to a billion, which takes far longer than the timeout.
+	query := "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1000000000) SELECT max(i) FROM n"
+	start := time.Now()
+	_, err = source.FetchData(t.Context(), query, nil)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestOpen_UnsupportedScheme(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	_, err := datasource.Open(t.Context(), datasource.Config{DSN: "oracle://host/db"}, logger)
+	require.ErrorIs(t, err, datasource.ErrUnsupportedScheme)
+}
@@ -0,0 +1,170 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nikoksr/assert-go"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+// ErrInvalidJob indicates a batch job whose report configuration failed validation.
+var ErrInvalidJob = errors.New("invalid job configuration")
+
+// Job is a single report of a batch run.
+type Job struct {
+	Name   string // Unique name of the job, used in logs and the summary.
+	Config Config
+	Err    error // Set if the configuration couldn't be prepared for the job, e.g. normalized; the job fails with it.
+}
+
+// JobResult is the outcome of a batch job. Err is nil if the report was generated successfully.
+type JobResult struct {
+	Name       string
+	OutputPath string
+	Duration   time.Duration
+	Err        error
+}
+
+// RunBatch generates the reports of all jobs using one shared set of data sources, running at most concurrency jobs at
+// a time. Every job is validated and run with its own timeout; a failing job doesn't stop the others. Jobs that haven't
+// started when ctx is cancelled fail with the context's error. Results are returned in the order of the jobs.
+func RunBatch(
+	ctx context.Context,
+	sources *datasource.Registry,
+	jobs []Job,
+	concurrency int,
+	logger *slog.Logger,
+) []JobResult {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(sources != nil, "data source registry must not be nil")
+	assert.Assert(concurrency > 0, "concurrency must be positive")
+	assert.Assert(logger != nil, "Logger must not be nil")
+
+	logger.Info("Starting batch", slog.Int("job_count", len(jobs)), slog.Int("concurrency", concurrency))
+
+	results := make([]JobResult, len(jobs))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, job := range jobs {
+		results[i] = JobResult{Name: job.Name, OutputPath: job.Config.OutputPath}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = fmt.Errorf("job not started: %w", ctx.Err())
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			jobLogger := logger.With(slog.String("job", job.Name))
+			startTime := time.Now()
+			results[i].Err = RunJob(ctx, sources, job, jobLogger)
+			results[i].Duration = time.Since(startTime)
+
+			if results[i].Err != nil {
+				jobLogger.Error(
+					"Batch job failed",
+					slog.String("error", results[i].Err.Error()),
+					slog.Duration("duration", results[i].Duration),
+				)
+				return
+			}
+			jobLogger.Info(
+				"Batch job finished successfully",
+				slog.String("output_path", job.Config.OutputPath),
+				slog.Duration("duration", results[i].Duration),
+			)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// RunJob validates the job's report configuration and generates the report within the job's timeout.
+func RunJob(ctx context.Context, sources *datasource.Registry, job Job, logger *slog.Logger) error {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(sources != nil, "data source registry must not be nil")
+	assert.Assert(logger != nil, "Logger must not be nil")
+
+	if err := ValidateJob(ctx, job); err != nil {
+		return err
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, job.Config.Timeout)
+	defer cancel()
+
+	if err := NewGenerator(sources, job.Config, logger).GenerateReport(jobCtx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return fmt.Errorf("report generation timed out after %s: %w", job.Config.Timeout, err)
+		}
+		return fmt.Errorf("report generation: %w", err)
+	}
+
+	return nil
+}
+
+// ValidateJob validates the job's report configuration. All problems are listed in the returned ErrInvalidJob error,
+// sorted by key. Jobs with an Err fail with it instead.
+func ValidateJob(ctx context.Context, job Job) error {
+	if job.Err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidJob, job.Err)
+	}
+
+	problems := job.Config.Valid(ctx)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(problems))
+	for key := range problems {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	details := make([]string, 0, len(keys))
+	for _, key := range keys {
+		details = append(details, key+": "+problems[key])
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidJob, strings.Join(details, "; "))
+}
+
+// WriteBatchSummary writes a human-readable summary of the batch results to w: one line per job followed by the
+// number of succeeded and failed jobs. It returns the number of failed jobs.
+func WriteBatchSummary(w io.Writer, results []JobResult) (int, error) {
+	failed := 0
+	var sb strings.Builder
+	for _, result := range results {
+		if errors.Is(result.Err, ErrReportIncomplete) {
+			failed++
+			fmt.Fprintf(&sb, "PART  %s -> %s: %v\n", result.Name, result.OutputPath, result.Err)
+			continue
+		}
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(&sb, "FAIL  %s: %v\n", result.Name, result.Err)
+			continue
+		}
+		fmt.Fprintf(&sb, "OK    %s -> %s (%s)\n", result.Name, result.OutputPath, result.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(&sb, "%d of %d jobs succeeded, %d failed\n", len(results)-failed, len(results), failed)
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return failed, fmt.Errorf("write batch summary: %w", err)
+	}
+	return failed, nil
+}
@@ -0,0 +1,98 @@
+package report_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+func TestRunBatch(t *testing.T) {
+	t.Parallel()
+
+	north := testWorkspace(t,
+		map[string]any{"A1": "{{ .params.region }}", "B1": "{{ .sales }}", "D1": "sales.sql"},
+		map[string]string{"sales.sql": "SELECT sales FROM s WHERE region = :region"},
+	)
+	north.Params = map[string]any{"region": "NORTH"}
+	missingQuery := testWorkspace(t, map[string]any{"A1": "{{ .sales }}", "D1": "missing.sql"}, nil)
+	invalid := testWorkspace(t, map[string]any{"A1": "{{ .sales }}", "D1": "sales.sql"}, nil)
+	invalid.DataSourceRefColumn = "1"
+	unprepared := testWorkspace(t, map[string]any{"A1": "{{ .sales }}", "D1": "sales.sql"}, nil)
+
+	source := &fakeDataSource{rows: map[string][]map[string]any{
+		"SELECT sales FROM s WHERE region = :region": {{"sales": 42}},
+	}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sources := datasource.NewRegistry(map[string]datasource.DataSource{datasource.DefaultSourceName: source}, logger)
+
+	results := report.RunBatch(t.Context(), sources, []report.Job{
+		{Name: "north", Config: north},
+		{Name: "missing-query", Config: missingQuery},
+		{Name: "invalid", Config: invalid},
+		{Name: "unprepared", Config: unprepared, Err: assert.AnError},
+	}, 1, logger)
+
+	require.Len(t, results, 4)
+	assert.Equal(t, "north", results[0].Name)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, north.OutputPath, results[0].OutputPath)
+	assert.Equal(t, [][]string{{"NORTH", "42"}}, readSheet(t, north.OutputPath))
+
+	assert.Equal(t, "missing-query", results[1].Name)
+	require.Error(t, results[1].Err)
+	assert.ErrorContains(t, results[1].Err, "referenced SQL file not found")
+
+	assert.Equal(t, "invalid", results[2].Name)
+	require.ErrorIs(t, results[2].Err, report.ErrInvalidJob)
+	assert.ErrorContains(t, results[2].Err, "data_source_ref_column")
+
+	assert.Equal(t, "unprepared", results[3].Name)
+	require.ErrorIs(t, results[3].Err, report.ErrInvalidJob)
+	require.ErrorIs(t, results[3].Err, assert.AnError)
+	assert.NoFileExists(t, unprepared.OutputPath)
+}
+
+func TestRunBatch_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t, map[string]any{"A1": "{{ .sales }}", "D1": "sales.sql"}, nil)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sources := datasource.NewRegistry(map[string]datasource.DataSource{"default": &fakeDataSource{}}, logger)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	results := report.RunBatch(ctx, sources, []report.Job{{Name: "a", Config: cfg}, {Name: "b", Config: cfg}}, 1, logger)
+
+	require.Len(t, results, 2)
+	for _, result := range results {
+		require.ErrorIs(t, result.Err, context.Canceled)
+	}
+}
+
+func TestWriteBatchSummary(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	failed, err := report.WriteBatchSummary(&buf, []report.JobResult{
+		{Name: "north", OutputPath: "/reports/north.xlsx"},
+		{Name: "south", OutputPath: "/reports/south.xlsx", Err: assert.AnError},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, failed)
+	assert.Equal(t,
+		"OK    north -> /reports/north.xlsx (0s)\n"+
+			"FAIL  south: "+assert.AnError.Error()+"\n"+
+			"1 of 2 jobs succeeded, 1 failed\n",
+		buf.String(),
+	)
+}
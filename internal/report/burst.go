@@ -0,0 +1,169 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nikoksr/assert-go"
+	"github.com/shopspring/decimal"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+// ErrInvalidBurstRow indicates a row of the driver query that can't be turned into the parameters of a report.
+var ErrInvalidBurstRow = errors.New("invalid burst driver row")
+
+// Burst generates one report per row of the driver query referenced by BurstQuery, e.g. one workbook per region
+// manager. The columns of a row are added to the report parameters, overriding those of the same name, so that every
+// other query and the output path template can use them; the output paths must differ for every row. The driver query
+// runs once, bound to the configured parameters, and all reports share the data sources of sources. Reports are
+// generated one after another, each validated and run with its own timeout like a batch job; a failing report doesn't
+// stop the others. The error is only non-nil if the reports couldn't be planned, e.g. because the driver query failed.
+func Burst(ctx context.Context, sources *datasource.Registry, cfg Config, logger *slog.Logger) ([]JobResult, error) {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(sources != nil, "data source registry must not be nil")
+	assert.Assert(logger != nil, "Logger must not be nil")
+	assert.Assert(cfg.BurstQuery != "", "burst query must not be empty")
+
+	if err := ValidateJob(ctx, Job{Name: "burst", Config: cfg}); err != nil {
+		return nil, err
+	}
+
+	logger = logger.With(slog.String("burst_query", cfg.BurstQuery))
+	logger.Info("Running burst driver query")
+
+	driverCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	rows, err := fetchDriverRows(driverCtx, sources, cfg)
+	if err != nil {
+		logger.Error("Burst driver query failed", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	jobs, err := burstJobs(cfg, rows, time.Now())
+	if err != nil {
+		logger.Error("Failed to plan burst reports", slog.String("error", err.Error()))
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		logger.Warn("Burst driver query returned no rows, no reports generated")
+		return nil, nil
+	}
+
+	logger.Info("Bursting report", slog.Int("report_count", len(jobs)))
+	return RunBatch(ctx, sources, jobs, 1, logger), nil
+}
+
+// fetchDriverRows runs the driver query of a burst with the configured parameters.
+func fetchDriverRows(ctx context.Context, sources *datasource.Registry, cfg Config) ([]map[string]any, error) {
+	ref := parseReference(cfg.BurstQuery)
+
+	name, err := resolveQueryPath(ref.Path)
+	if err != nil {
+		return nil, err
+	}
+	query, err := fs.ReadFile(os.DirFS(cfg.QueriesDir), name)
+	if err != nil {
+		return nil, fmt.Errorf("read burst driver SQL file %q: %w", ref.Path, err)
+	}
+	if strings.TrimSpace(string(query)) == "" {
+		return nil, fmt.Errorf("burst driver SQL file %q is empty", ref.Path)
+	}
+	if err := datasource.CheckStatement(string(query), cfg.AllowedStatements); err != nil {
+		return nil, fmt.Errorf("burst driver SQL file %q: %w", ref.Path, err)
+	}
+
+	source, err := sources.Lookup(ref.Source)
+	if err != nil {
+		return nil, fmt.Errorf("burst driver SQL file %q: %w", ref.Path, err)
+	}
+	rows, err := source.FetchRows(ctx, string(query), cfg.Params)
+	if err != nil {
+		return nil, fmt.Errorf("fetch rows using burst driver query from %q: %w", ref.Path, err)
+	}
+
+	return rows, nil
+}
+
+// burstJobs turns the rows of a driver query into one job per row. Jobs are named after their output file.
+func burstJobs(cfg Config, rows []map[string]any, now time.Time) ([]Job, error) {
+	jobs := make([]Job, 0, len(rows))
+	seenOutputs := make(map[string]int, len(rows))
+	for i, row := range rows {
+		jobCfg := cfg
+		jobCfg.BurstQuery = ""
+		jobCfg.Params = make(map[string]any, len(cfg.Params)+len(row))
+		maps.Copy(jobCfg.Params, cfg.Params)
+
+		for _, column := range slices.Sorted(maps.Keys(row)) {
+			value, err := burstParamValue(column, row[column])
+			if err != nil {
+				return nil, fmt.Errorf("%w %d: %w", ErrInvalidBurstRow, i+1, err)
+			}
+			jobCfg.Params[column] = value
+		}
+
+		outputPath, err := jobCfg.RenderOutputPath(now)
+		if err != nil {
+			return nil, fmt.Errorf("%w %d: output path: %w", ErrInvalidBurstRow, i+1, err)
+		}
+		jobCfg.OutputPath = filepath.Clean(outputPath)
+		if other, exists := seenOutputs[jobCfg.OutputPath]; exists {
+			return nil, fmt.Errorf("%w %d: same output path %q as row %d", ErrInvalidBurstRow, i+1,
+				jobCfg.OutputPath, other)
+		}
+		seenOutputs[jobCfg.OutputPath] = i + 1
+
+		name := strings.TrimSuffix(filepath.Base(jobCfg.OutputPath), filepath.Ext(jobCfg.OutputPath))
+		jobs = append(jobs, Job{Name: name, Config: jobCfg})
+	}
+
+	return jobs, nil
+}
+
+// burstParamValue converts the value of a driver query column into a report parameter value of a supported type.
+func burstParamValue(column string, value any) (any, error) {
+	if !IsValidParamName(column) {
+		return nil, fmt.Errorf("column %q is not a valid parameter name; alias it in the driver query", column)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("column %q is NULL", column)
+	case []byte:
+		return string(v), nil
+	case datasource.InfiniteTime:
+		return nil, fmt.Errorf("column %q is an infinite date", column)
+	case decimal.Decimal:
+		return v.String(), nil // From DecimalsExact; bound in its string form, which keeps every digit.
+	}
+	if isSupportedParamValue(value) {
+		return value, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() { //nolint:exhaustive // All other kinds are unsupported.
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return int64(rv.Uint()), nil //nolint:gosec // At most 32 bits.
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	default:
+		return nil, fmt.Errorf("column %q has unsupported type %T", column, value)
+	}
+}
@@ -0,0 +1,121 @@
+package report_test
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+func TestBurst(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{"A1": "{{ .params.manager }}", "B1": "{{ .sales }}", "D1": "sales.sql"},
+		map[string]string{
+			"managers.sql": "SELECT region, manager, id FROM managers",
+			"sales.sql":    "SELECT sales FROM sales WHERE region = :region",
+		},
+	)
+	cfg.BurstQuery = "managers.sql"
+	outputDir := filepath.Dir(cfg.OutputPath)
+	cfg.OutputPath = filepath.Join(outputDir, "sales_{{ .params.year }}_{{ .params.region }}.xlsx")
+	cfg.Params = map[string]any{"year": int64(2026), "region": "ALL"}
+
+	source := &fakeDataSource{rows: map[string][]map[string]any{
+		"SELECT region, manager, id FROM managers": {
+			{
+				"region": "NORTH", "manager": []byte("Ada"), "id": int32(1),
+				"quota": decimal.RequireFromString("12345678901234567890.12"),
+			},
+			{"region": "SOUTH", "manager": "Grace", "id": int32(2), "quota": decimal.RequireFromString("0.5")},
+		},
+		"SELECT sales FROM sales WHERE region = :region": {{"sales": 42}},
+	}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sources := datasource.NewRegistry(map[string]datasource.DataSource{datasource.DefaultSourceName: source}, logger)
+
+	results, err := report.Burst(t.Context(), sources, cfg, logger)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	for i, want := range []struct{ name, manager string }{{"sales_2026_NORTH", "Ada"}, {"sales_2026_SOUTH", "Grace"}} {
+		require.NoError(t, results[i].Err)
+		assert.Equal(t, want.name, results[i].Name)
+		assert.Equal(t, filepath.Join(outputDir, want.name+".xlsx"), results[i].OutputPath)
+		assert.Equal(t, [][]string{{want.manager, "42"}}, readSheet(t, results[i].OutputPath))
+	}
+
+	// The driver query is bound to the configured parameters, every other query to those merged with its row.
+	require.Len(t, source.params, 3)
+	assert.Equal(t, "ALL", source.params[0]["region"])
+	assert.Equal(t, map[string]any{
+		"year": int64(2026), "region": "NORTH", "manager": "Ada", "id": int64(1), "quota": "12345678901234567890.12",
+	}, source.params[1])
+	assert.Equal(t, "SOUTH", source.params[2]["region"])
+}
+
+func TestBurst_InvalidDriverRows(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		rows    []map[string]any
+		wantErr string
+	}{
+		{
+			name:    "Same Output Path",
+			rows:    []map[string]any{{"region": "NORTH"}, {"region": "NORTH"}},
+			wantErr: `row 2: same output path`,
+		},
+		{name: "NULL Column", rows: []map[string]any{{"region": nil}}, wantErr: `row 1: column "region" is NULL`},
+		{
+			name:    "Invalid Column Name",
+			rows:    []map[string]any{{"region": "NORTH", "count(*)": int64(3)}},
+			wantErr: "not a valid parameter name",
+		},
+		{
+			name:    "Unsupported Type",
+			rows:    []map[string]any{{"region": []string{"NORTH"}}},
+			wantErr: "unsupported type []string",
+		},
+		{
+			name:    "Column Missing From Output Path",
+			rows:    []map[string]any{{"area": "NORTH"}},
+			wantErr: "row 1: output path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testWorkspace(t,
+				map[string]any{"A1": "{{ .sales }}", "D1": "sales.sql"},
+				map[string]string{"regions.sql": "SELECT region FROM regions", "sales.sql": "SELECT sales FROM sales"},
+			)
+			cfg.BurstQuery = "regions.sql"
+			cfg.OutputPath = filepath.Join(filepath.Dir(cfg.OutputPath), "{{ .params.region }}.xlsx")
+
+			source := &fakeDataSource{rows: map[string][]map[string]any{"SELECT region FROM regions": tt.rows}}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			sources := datasource.NewRegistry(map[string]datasource.DataSource{datasource.DefaultSourceName: source}, logger)
+
+			results, err := report.Burst(t.Context(), sources, cfg, logger)
+			require.ErrorIs(t, err, report.ErrInvalidBurstRow)
+			require.ErrorContains(t, err, tt.wantErr)
+			assert.Empty(t, results)
+
+			_, statErr := os.Stat(filepath.Dir(cfg.OutputPath))
+			assert.ErrorIs(t, statErr, os.ErrNotExist, "no report may be written")
+		})
+	}
+}
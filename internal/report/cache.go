@@ -0,0 +1,172 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+// ErrQueryOutsideQueriesDir indicates a reference to a SQL file outside the queries directory.
+var ErrQueryOutsideQueriesDir = errors.New("SQL file is outside the queries directory")
+
+// resolveQueryPath returns the name of a referenced SQL file in the queries file system. References are relative to the
+// queries directory, also with a leading slash, e.g. "/sales/total.sql"; references leading outside of it are rejected.
+func resolveQueryPath(ref string) (string, error) {
+	name := path.Clean(strings.TrimLeft(filepath.ToSlash(ref), "/"))
+	if !fs.ValidPath(name) {
+		return "", fmt.Errorf("%w: %q", ErrQueryOutsideQueriesDir, ref)
+	}
+	return name, nil
+}
+
+// readQueryFile returns the contents of a SQL file by its path in the reference column, reading it only the first time
+// it is requested in a run. See resolveQueryPath.
+func (g *Generator) readQueryFile(ref string) ([]byte, error) {
+	name, err := resolveQueryPath(ref)
+	if err != nil {
+		return nil, err
+	}
+	if content, ok := g.queryFiles[name]; ok {
+		return content, nil
+	}
+
+	if g.queryFS == nil {
+		return nil, errors.New("neither a queries directory nor a queries file system is configured")
+	}
+	content, err := fs.ReadFile(g.queryFS, name)
+	if err != nil {
+		return nil, err
+	}
+	g.queryFiles[name] = content
+	return content, nil
+}
+
+// queryCacheKey identifies the result of a query: the data source, the reference mode, the normalized query text and
+// everything else the result depends on, i.e. the parameters and the settings values are converted with.
+func (g *Generator) queryCacheKey(sourceName string, table bool, query string) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "source=%s\ntable=%t\nquery=%s\n", sourceName, table, datasource.NormalizeQuery(query))
+	fmt.Fprintf(hash, "timezone=%s\ndecimals=%s\n", g.config.Timezone, g.config.Decimals)
+
+	names := make([]string, 0, len(g.config.Params))
+	for name := range g.config.Params {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		value := g.config.Params[name]
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(time.RFC3339Nano) // Same instant, same key, regardless of the monotonic clock reading.
+		}
+		fmt.Fprintf(hash, "param.%s=%T:%v\n", name, g.config.Params[name], value)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// diskCache persists query results across runs, so that iterating on a template's layout doesn't run every query
+// again. Results are stored as one gob file per cache key and expire ttl after they were written. Note that keys don't
+// cover the DSN of a data source; results of a data source that was pointed elsewhere are served until they expire.
+type diskCache struct {
+	dir    string
+	ttl    time.Duration
+	logger *slog.Logger
+}
+
+// cachedResult is the file format of the disk cache.
+type cachedResult struct {
+	Record  map[string]any
+	Records []map[string]any
+}
+
+func newDiskCache(dir string, ttl time.Duration, logger *slog.Logger) *diskCache {
+	// Values of result columns are stored as interfaces; gob needs to know their concrete types beyond the built-in
+	// ones. Registering a type again is a no-op.
+	gob.Register(time.Time{})
+	gob.Register(decimal.Decimal{})
+	gob.Register(datasource.InfiniteTime{})
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+
+	return &diskCache{dir: dir, ttl: ttl, logger: logger.With(slog.String("cache_dir", dir))}
+}
+
+// load returns the cached result for key if there is one that hasn't expired yet.
+func (c *diskCache) load(key string) (cachedResult, bool) {
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.ttl {
+		return cachedResult{}, false
+	}
+
+	f, err := os.Open(path) //nolint:gosec // The path is derived from a hash within the configured cache directory.
+	if err != nil {
+		c.logger.Warn("Failed to open cached query result", slog.String("path", path), slog.String("error", err.Error()))
+		return cachedResult{}, false
+	}
+	defer f.Close()
+
+	var result cachedResult
+	if err := gob.NewDecoder(f).Decode(&result); err != nil {
+		c.logger.Warn("Failed to decode cached query result", slog.String("path", path), slog.String("error", err.Error()))
+		return cachedResult{}, false
+	}
+
+	c.logger.Debug("Using cached query result", slog.String("path", path), slog.Time("cached_at", info.ModTime()))
+	return result, true
+}
+
+// store writes the result for key. Failing to do so only costs running the query again next time, so errors are logged
+// rather than returned.
+func (c *diskCache) store(key string, result cachedResult) {
+	if err := c.write(key, result); err != nil {
+		c.logger.Warn("Failed to cache query result", slog.String("error", err.Error()))
+	}
+}
+
+func (c *diskCache) write(key string, result cachedResult) (err error) {
+	if err := os.MkdirAll(c.dir, 0o750); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+
+	// Write to a temporary file first, so that concurrent runs never read a partially written result.
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create cache file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := gob.NewEncoder(tmp).Encode(result); err != nil {
+		return errors.Join(fmt.Errorf("encode query result: %w", err), tmp.Close())
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		return fmt.Errorf("move cache file into place: %w", err)
+	}
+
+	return nil
+}
+
+func (c *diskCache) path(key string) string {
+	return filepath.Join(c.dir, key+".gob")
+}
@@ -0,0 +1,166 @@
+package report
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xuri/excelize/v2"
+)
+
+// Number formats that mark a template cell as text. Such cells opt out of type coercion and keep the template output
+// as written.
+const (
+	builtInTextNumFmt = 49 // Excel's built-in "Text" format.
+	textNumFmtCode    = "@"
+)
+
+// coercionDateLayouts are the layouts template output is parsed with to recognize dates, most specific first. The last
+// one is how text/template prints a time.Time.
+var coercionDateLayouts = []string{ //nolint:gochecknoglobals // Read-only lookup table.
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	time.DateTime,
+	time.DateOnly,
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+}
+
+// formattingFuncs are the template functions that render a value as formatted text, e.g. "12.50" or "2026-10". Cell
+// templates calling one of them opt out of type coercion, which would undo the formatting. round isn't one of them: it
+// returns a number.
+var formattingFuncs = map[string]bool{ //nolint:gochecknoglobals // Read-only lookup table.
+	"formatNumber":       true,
+	"formatNumberLocale": true,
+	"percent":            true,
+	"date":               true,
+}
+
+// coerceTemplateOutput converts the string produced by a cell template back into a number, boolean or date if it is
+// unambiguously one, so that computed cells stay numeric in the workbook. Only plain decimal notation counts as a
+// number; numbers with more digits than a float holds become decimals, which are only written as numbers if that loses
+// no precision. Anything else, including numbers with leading zeros like ZIP codes or IDs and exponent notation like
+// "2E10", is returned unchanged.
+func coerceTemplateOutput(output string) any {
+	trimmed := strings.TrimSpace(output)
+	if trimmed == "" || hasLeadingZero(trimmed) {
+		return output
+	}
+
+	if i, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+		return i
+	}
+	if isDecimalNumber(trimmed) {
+		if f, err := strconv.ParseFloat(trimmed, 64); err == nil && !math.IsInf(f, 0) {
+			if d, err := decimal.NewFromString(trimmed); err == nil && !isExactFloat(d, f) {
+				return d
+			}
+			return f
+		}
+	}
+
+	switch strings.ToLower(trimmed) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	for _, layout := range coercionDateLayouts {
+		if t, err := time.Parse(layout, trimmed); err == nil {
+			return t
+		}
+	}
+
+	return output
+}
+
+// hasLeadingZero reports whether s is a number with a leading zero in its integer part, e.g. "007" or "-01.5".
+func hasLeadingZero(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	return len(s) > 1 && s[0] == '0' && s[1] >= '0' && s[1] <= '9'
+}
+
+// isDecimalNumber reports whether s only consists of characters of a plain decimal number, which rules out the special
+// values "NaN" and "Inf" as well as exponent and hexadecimal notation accepted by strconv.ParseFloat.
+func isDecimalNumber(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && !strings.ContainsRune("+-.", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// callsFormattingFunc reports whether a parsed cell template calls one of the formattingFuncs.
+func callsFormattingFunc(tmpl *template.Template) bool {
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && hasFormattingFunc(t.Tree.Root) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFormattingFunc reports whether the parse tree below node calls one of the formattingFuncs.
+func hasFormattingFunc(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.IdentifierNode:
+		return formattingFuncs[n.Ident]
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, child := range n.Nodes {
+			if hasFormattingFunc(child) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return hasFormattingFunc(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, cmd := range n.Cmds {
+			if hasFormattingFunc(cmd) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if hasFormattingFunc(arg) {
+				return true
+			}
+		}
+	case *parse.ChainNode:
+		return hasFormattingFunc(n.Node)
+	case *parse.IfNode:
+		return hasFormattingFunc(&n.BranchNode)
+	case *parse.RangeNode:
+		return hasFormattingFunc(&n.BranchNode)
+	case *parse.WithNode:
+		return hasFormattingFunc(&n.BranchNode)
+	case *parse.BranchNode:
+		return hasFormattingFunc(n.Pipe) || hasFormattingFunc(n.List) || hasFormattingFunc(n.ElseList)
+	case *parse.TemplateNode:
+		return hasFormattingFunc(n.Pipe)
+	}
+	return false
+}
+
+// isTextCell reports whether the cell is formatted as text, which opts it out of type coercion.
+func isTextCell(file *excelize.File, sheetName, cell string) bool {
+	styleID, err := file.GetCellStyle(sheetName, cell)
+	if err != nil || styleID == 0 {
+		return false
+	}
+	style, err := file.GetStyle(styleID)
+	if err != nil {
+		return false
+	}
+	return style.NumFmt == builtInTextNumFmt || (style.CustomNumFmt != nil && *style.CustomNumFmt == textNumFmtCode)
+}
@@ -0,0 +1,93 @@
+package report_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+func TestCoerceTemplateOutput(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		output   string
+		expected any
+	}{
+		{output: "42", expected: int64(42)},
+		{output: " -7 ", expected: int64(-7)},
+		{output: "12.50", expected: 12.5},
+		{output: "0", expected: int64(0)},
+		{output: "0.25", expected: 0.25},
+		{output: "12345678901234567890.12", expected: decimal.RequireFromString("12345678901234567890.12")},
+		{output: "TRUE", expected: true},
+		{output: "false", expected: false},
+		{output: "2026-10-16", expected: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{output: "2026-10-16 08:30:00", expected: time.Date(2026, 10, 16, 8, 30, 0, 0, time.UTC)},
+		{output: "2026-10-16 08:30:00 +0000 UTC", expected: time.Date(2026, 10, 16, 8, 30, 0, 0, time.UTC)},
+		// Left as written.
+		{output: "", expected: ""},
+		{output: "00123", expected: "00123"},
+		{output: "-01.5", expected: "-01.5"},
+		{output: "NaN", expected: "NaN"},
+		{output: "Inf", expected: "Inf"},
+		{output: "0x1p-2", expected: "0x1p-2"},
+		{output: "1,234", expected: "1,234"},
+		{output: "1e3", expected: "1e3"},
+		{output: "2E10", expected: "2E10"},
+		{output: "1", expected: int64(1)},
+		{output: "yes", expected: "yes"},
+		{output: "42 apples", expected: "42 apples"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.output, func(t *testing.T) {
+			t.Parallel()
+
+			actual := report.CoerceTemplateOutput(tc.output)
+			if expectedTime, ok := tc.expected.(time.Time); ok {
+				actualTime, isTime := actual.(time.Time)
+				assert.True(t, isTime, "expected a time.Time, got %T", actual)
+				assert.True(t, expectedTime.Equal(actualTime), "expected %s, got %s", expectedTime, actualTime)
+				return
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestProcessTemplate_Coercion(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]any{"x": 12.5, "ratio": 0.125, "day": time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)}
+
+	testCases := []struct {
+		name     string
+		template string
+		expected any
+	}{
+		{name: "Arithmetic", template: "{{ add .x 1 }}", expected: 13.5},
+		{name: "Round", template: "{{ round 1 .x }}", expected: 12.5},
+		{name: "Format Number", template: "{{ formatNumber 2 .x }}", expected: "12.50"},
+		{name: "Format Number Piped", template: "{{ .x | formatNumber 2 }}", expected: "12.50"},
+		{name: "Format Number Locale", template: `{{ formatNumberLocale "en" 1 .x }}`, expected: "12.5"},
+		{name: "Format Number In Condition", template: "{{ if .x }}{{ formatNumber 1 .x }}{{ end }}", expected: "12.5"},
+		{name: "Percent", template: "{{ percent 1 .ratio }}", expected: "12.5%"},
+		{name: "Date", template: `{{ .day | date "2006-01-02" }}`, expected: "2026-10-16"},
+		{name: "Exponent Notation", template: `{{ "2E10" }}`, expected: "2E10"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := report.ProcessTemplate(tc.template, data, true)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package report
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// joinCollection renders the elements of a slice, or the fields of a map as "key: value" in key order, separated by
+// sep. NULL elements are rendered as empty strings and nested arrays and objects as JSON.
+func joinCollection(rv reflect.Value, sep string) (string, error) {
+	var parts []string
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := range rv.Len() {
+			part, err := collectionElement(rv.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		}
+	case reflect.Map:
+		keys := rv.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+		})
+		for _, key := range keys {
+			part, err := collectionElement(rv.MapIndex(key).Interface())
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, fmt.Sprintf("%v: %s", key.Interface(), part))
+		}
+	default:
+		value := rv.Interface()
+		return "", fmt.Errorf("%w: %v (%T) is not an array or object", ErrInvalidFuncArg, value, value)
+	}
+
+	return strings.Join(parts, sep), nil
+}
+
+// collectionElement renders a single element of a joined collection.
+func collectionElement(element any) (string, error) {
+	switch v := element.(type) {
+	case nil:
+		return "", nil
+	case time.Time:
+		if isDateOnly(v) {
+			return v.Format(time.DateOnly), nil
+		}
+		return v.Format(time.DateTime), nil
+	}
+
+	rendered, err := encodeComplexTypes(element, CollectionFormatJSON)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(rendered), nil
+}
+
+// lookupPath resolves a path of keys through nested objects, e.g. ["settings", "level"] for {{ .settings.level }}. It
+// reports false if a key is missing or addresses something other than an object.
+func lookupPath(data map[string]any, path []string) (any, bool) {
+	var value any = data
+	for _, key := range path {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
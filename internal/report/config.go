@@ -4,21 +4,101 @@ package report
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"time"
 )
 
+// Defaults of the optional date settings.
+const (
+	DefaultDateFormat     = "yyyy-mm-dd"          // Number format of date cells without one.
+	DefaultDateTimeFormat = "yyyy-mm-dd hh:mm:ss" // Number format of date-time cells without one.
+)
+
+// Renderings of infinite dates and timestamps.
+const (
+	InfinityText  = "text"  // As the text "infinity" or "-infinity"; the default.
+	InfinityEmpty = "empty" // As an empty cell.
+	InfinityDate  = "date"  // As the latest (9999-12-31) or earliest (1900-01-01) date Excel supports.
+)
+
+// Representations of exact numeric database types like NUMERIC.
+const (
+	DecimalsFloat = "float" // Converted to float64 when fetched; the default.
+	DecimalsExact = "exact" // Kept as exact decimals through templates and only converted when written.
+)
+
+// Renderings of whole arrays and objects written to a cell.
+const (
+	CollectionFormatJSON    = "json"    // As JSON, e.g. ["alpha","beta"]; the default.
+	CollectionFormatComma   = "comma"   // As a comma separated list, e.g. "alpha, beta" or "level: 10, mode: fast".
+	CollectionFormatNewline = "newline" // As a list with one element per line.
+)
+
+// Behaviors when the data of a referenced row can't be filled in.
+const (
+	OnErrorAbort    = "abort"    // Abort generation with the row's error. The default.
+	OnErrorContinue = "continue" // Mark the row's placeholder cells, list the failure in ErrorsSheetName and go on.
+)
+
+// DefaultErrorMarker is written into the placeholder cells of failed rows unless configured otherwise.
+const DefaultErrorMarker = "#ERROR"
+
 // excelColumnRegex validates standard Excel column names (e.g., A, Z, AA, XFD).
 var excelColumnRegex = regexp.MustCompile(`^[A-Z]+$`)
 
+// statementTypeRegex validates the entries of AllowedStatements, which are single SQL keywords.
+var statementTypeRegex = regexp.MustCompile(`^[A-Za-z]+$`)
+
 type Config struct {
-	TemplatePath        string        // Absolute path to the input Excel template file (.xlsx).
-	DataSourceRefColumn string        // Uppercase Excel column letter indicating the SQL file reference (e.g., "R").
-	QueriesDir          string        // Absolute base directory for resolving SQL file paths found in the reference column.
-	OutputPath          string        // Absolute path where the generated report will be saved.
-	Timeout             time.Duration // Maximum duration allowed for the entire report generation process.
+	TemplatePath        string         // Absolute path to the input Excel template file (.xlsx).
+	DataSourceRefColumn string         // Uppercase Excel column letter indicating the SQL file reference (e.g., "R").
+	QueriesDir          string         // Absolute base directory for resolving SQL file paths found in the reference column.
+	OutputPath          string         // Absolute path where the generated report will be saved.
+	Timeout             time.Duration  // Maximum duration allowed for the entire report generation process.
+	Params              map[string]any // Typed report parameters, bound to ":name" in queries and exposed to templates.
+
+	// Timezone is the IANA name of the zone timestamps with a time zone are converted into, e.g. "Europe/Berlin".
+	// Defaults to UTC.
+	Timezone       string
+	DateFormat     string // Number format of date cells without one; defaults to DefaultDateFormat.
+	DateTimeFormat string // Number format of date-time cells without one; defaults to DefaultDateTimeFormat.
+	Infinity       string // Rendering of infinite dates: InfinityText (default), InfinityEmpty or InfinityDate.
+	Decimals       string // Representation of exact numeric types: DecimalsFloat (default) or DecimalsExact.
+
+	// CollectionFormat is the rendering of arrays and objects written to a cell as a whole: CollectionFormatJSON
+	// (default), CollectionFormatComma or CollectionFormatNewline.
+	CollectionFormat string
+
+	OnError     string // Behavior when a row fails: OnErrorAbort (default) or OnErrorContinue.
+	ErrorMarker string // Written into the placeholder cells of failed rows with OnErrorContinue; see DefaultErrorMarker.
+
+	// KeepFailed keeps the partially generated workbook of a failed run at FailedOutputPath for debugging. The output
+	// path itself is never touched by a failed run.
+	KeepFailed bool
+
+	// Concurrency is the maximum number of queries run at the same time. Zero or one runs them one after another.
+	Concurrency int
+
+	// CacheTTL enables caching query results on disk, in CacheDir, for the given duration. Zero disables the cache;
+	// identical queries still run only once per report.
+	CacheTTL time.Duration
+	CacheDir string // Absolute path of the directory cached query results are stored in.
+
+	// Snapshot runs all queries against one consistent snapshot per data source, taken when processing starts, so that
+	// all figures of the report are consistent with each other. Only supported by PostgreSQL.
+	Snapshot bool
+
+	// BurstQuery references a driver SQL file like the reference column does, e.g. "regions.sql" or
+	// "crm:managers.sql". If set, one report is generated per row the driver query returns, with the row's columns
+	// added to Params; OutputPath must then be a template that tells the reports apart. See Burst.
+	BurstQuery string
+
+	// AllowedStatements restricts queries to the given statement types, i.e. their first keyword, e.g. "SELECT" and
+	// "WITH". Empty allows any single statement. Queries are checked before any of them runs.
+	AllowedStatements []string
 }
 
 func (c Config) Valid(_ context.Context) map[string]string {
@@ -61,14 +141,111 @@ func (c Config) Valid(_ context.Context) map[string]string {
 		problems["output_path"] = "path must be absolute (normalization likely failed)"
 	}
 
+	// Validate BurstQuery
+	if c.BurstQuery != "" {
+		ref := parseReference(c.BurstQuery)
+		switch {
+		case ref.Path == "":
+			problems["burst"] = "must reference a SQL file"
+		case ref.Mode == referenceModeTable:
+			problems["burst"] = fmt.Sprintf("must not be tagged %s; the driver query always returns rows", tableModeTag)
+		default:
+			name, err := resolveQueryPath(ref.Path)
+			if err != nil {
+				problems["burst"] = fmt.Sprintf("SQL file %q is outside the queries directory", ref.Path)
+			} else if c.QueriesDir != "" {
+				if _, err := fs.Stat(os.DirFS(c.QueriesDir), name); err != nil {
+					problems["burst"] = fmt.Sprintf("path error: %v", err)
+				}
+			}
+		}
+		if !IsOutputPathTemplate(c.OutputPath) {
+			problems["output_path"] = "must be a template like \"{{ .params.region }}.xlsx\" when bursting, so that " +
+				"every report gets its own file"
+		}
+	}
+
 	// Validate Timeout
 	if c.Timeout <= 0 {
 		problems["timeout"] = "must be a positive duration"
 	}
 
+	// Validate Timezone
+	if _, err := c.Location(); err != nil {
+		problems["timezone"] = err.Error()
+	}
+
+	// Validate Infinity
+	switch c.Infinity {
+	case "", InfinityText, InfinityEmpty, InfinityDate:
+	default:
+		problems["infinity"] = fmt.Sprintf("must be one of %q, %q or %q, got: %s",
+			InfinityText, InfinityEmpty, InfinityDate, c.Infinity)
+	}
+
+	// Validate Decimals
+	if c.Decimals != "" && c.Decimals != DecimalsFloat && c.Decimals != DecimalsExact {
+		problems["decimals"] = fmt.Sprintf("must be %q or %q, got: %s", DecimalsFloat, DecimalsExact, c.Decimals)
+	}
+
+	// Validate CollectionFormat
+	switch c.CollectionFormat {
+	case "", CollectionFormatJSON, CollectionFormatComma, CollectionFormatNewline:
+	default:
+		problems["collection_format"] = fmt.Sprintf("must be one of %q, %q or %q, got: %s",
+			CollectionFormatJSON, CollectionFormatComma, CollectionFormatNewline, c.CollectionFormat)
+	}
+
+	// Validate OnError
+	if c.OnError != "" && c.OnError != OnErrorAbort && c.OnError != OnErrorContinue {
+		problems["on_error"] = fmt.Sprintf("must be %q or %q, got: %s", OnErrorAbort, OnErrorContinue, c.OnError)
+	}
+
+	// Validate Concurrency
+	if c.Concurrency < 0 {
+		problems["concurrency"] = fmt.Sprintf("must not be negative, got: %d", c.Concurrency)
+	}
+
+	// Validate CacheTTL and CacheDir
+	if c.CacheTTL < 0 {
+		problems["cache_ttl"] = fmt.Sprintf("must not be negative, got: %s", c.CacheTTL)
+	} else if c.CacheTTL > 0 && !filepath.IsAbs(c.CacheDir) {
+		problems["cache_dir"] = "path must be absolute (normalization likely failed)"
+	}
+
+	// Validate AllowedStatements
+	for i, statementType := range c.AllowedStatements {
+		if !statementTypeRegex.MatchString(statementType) {
+			problems[fmt.Sprintf("allowed_statements[%d]", i)] = fmt.Sprintf(
+				"must be a single SQL keyword like SELECT, got: %q", statementType)
+		}
+	}
+
+	// Validate Params
+	for name, value := range c.Params {
+		if !IsValidParamName(name) {
+			problems["params."+name] = "name must start with a letter or underscore and contain only letters, digits " +
+				"and underscores"
+		} else if !isSupportedParamValue(value) {
+			problems["params."+name] = fmt.Sprintf("unsupported value type %T", value)
+		}
+	}
+
 	return problems
 }
 
+// Location returns the time zone named by Timezone, or UTC if it is empty.
+func (c Config) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("load time zone %q: %w", c.Timezone, err)
+	}
+	return loc, nil
+}
+
 func isValidExcelColumnName(column string) bool {
 	return excelColumnRegex.MatchString(column)
 }
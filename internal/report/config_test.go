@@ -20,6 +20,7 @@ func TestReportConfig_Valid(t *testing.T) {
 	baseTmpDir := t.TempDir()
 	existingQueriesDir := filepath.Join(baseTmpDir, "queries")
 	require.NoError(t, os.Mkdir(existingQueriesDir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(existingQueriesDir, "regions.sql"), []byte("SELECT 1"), 0o600))
 	existingTemplateFile, err := os.CreateTemp(baseTmpDir, "template-*.xlsx")
 	require.NoError(t, err)
 	existingTemplatePath := existingTemplateFile.Name()
@@ -237,6 +238,203 @@ func TestReportConfig_Valid(t *testing.T) {
 			expectedProblemKey:   "timeout",
 			expectedErrSubstring: "must be a positive duration",
 		},
+		// --- Date Validations ---
+		{
+			name: "Valid Timezone and Infinity",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.Timezone = "Europe/Berlin"
+				c.Infinity = report.InfinityDate
+				return c
+			}(),
+			expectValid: true,
+		},
+		{
+			name: "Unknown Timezone",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.Timezone = "Mars/Olympus_Mons"
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "timezone",
+			expectedErrSubstring: "Mars/Olympus_Mons",
+		},
+		{
+			name: "Unsupported Infinity",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.Infinity = "forever"
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "infinity",
+			expectedErrSubstring: "got: forever",
+		},
+		{
+			name: "Unsupported Decimals",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.Decimals = "fixed"
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "decimals",
+			expectedErrSubstring: "got: fixed",
+		},
+		{
+			name: "Unsupported Collection Format",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.CollectionFormat = "csv"
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "collection_format",
+			expectedErrSubstring: "got: csv",
+		},
+		{
+			name: "Unsupported OnError",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.OnError = "ignore"
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "on_error",
+			expectedErrSubstring: "got: ignore",
+		},
+		{
+			name: "Negative Concurrency",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.Concurrency = -1
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "concurrency",
+			expectedErrSubstring: "must not be negative",
+		},
+		{
+			name: "Allowed Statements",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.AllowedStatements = []string{"SELECT", "with"}
+				return c
+			}(),
+			expectValid: true,
+		},
+		{
+			name: "Allowed Statement Not A Keyword",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.AllowedStatements = []string{"SELECT", "SELECT INTO"}
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "allowed_statements[1]",
+			expectedErrSubstring: "single SQL keyword",
+		},
+		{
+			name: "Burst",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.BurstQuery = "regions.sql"
+				c.OutputPath = filepath.Join(baseTmpDir, "{{ .params.region }}.xlsx")
+				return c
+			}(),
+			expectValid: true,
+		},
+		{
+			name: "Burst Without Output Path Template",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.BurstQuery = "regions.sql"
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "output_path",
+			expectedErrSubstring: "must be a template",
+		},
+		{
+			name: "Burst Driver Not Found",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.BurstQuery = "missing.sql"
+				c.OutputPath = filepath.Join(baseTmpDir, "{{ .params.region }}.xlsx")
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "burst",
+			expectedErrSubstring: "path error",
+		},
+		{
+			name: "Burst Driver As Table",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.BurstQuery = "[table] regions.sql"
+				c.OutputPath = filepath.Join(baseTmpDir, "{{ .params.region }}.xlsx")
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "burst",
+			expectedErrSubstring: "must not be tagged",
+		},
+		{
+			name: "Negative Cache TTL",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.CacheTTL = -time.Minute
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "cache_ttl",
+			expectedErrSubstring: "must not be negative",
+		},
+		{
+			name: "Relative Cache Dir",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.CacheTTL = time.Minute
+				c.CacheDir = "cache"
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "cache_dir",
+			expectedErrSubstring: "path must be absolute",
+		},
+		// --- Params Validations ---
+		{
+			name: "Valid Params",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.Params = map[string]any{"region": "NORTH", "year": int64(2026), "month": time.Now()}
+				return c
+			}(),
+			expectValid: true,
+		},
+		{
+			name: "Invalid Param Name",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.Params = map[string]any{"my-region": "NORTH"}
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "params.my-region",
+			expectedErrSubstring: "must start with a letter or underscore",
+		},
+		{
+			name: "Unsupported Param Value",
+			cfg: func() report.Config {
+				c := validBaseCfg
+				c.Params = map[string]any{"regions": []string{"NORTH"}}
+				return c
+			}(),
+			expectValid:          false,
+			expectedProblemKey:   "params.regions",
+			expectedErrSubstring: "unsupported value type []string",
+		},
 		// --- Multiple Errors ---
 		{
 			name: "Multiple Errors",
@@ -0,0 +1,82 @@
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+// dateStyleKey identifies a cell style derived from a template style by adding a date number format.
+type dateStyleKey struct {
+	baseStyleID int
+	numFmt      string
+}
+
+// excelTime returns the wall clock time of t in its location, as UTC. Excel has no notion of time zones and excelize
+// writes the UTC instant of a time, so this makes the cell show the time as seen in t's location.
+func excelTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// isDateOnly reports whether t has no time of day.
+func isDateOnly(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
+// infinityValue renders an infinite date or timestamp the way the report is configured to.
+func (g *Generator) infinityValue(value datasource.InfiniteTime) any {
+	switch g.config.Infinity {
+	case InfinityEmpty:
+		return nil
+	case InfinityDate:
+		if value.Negative {
+			return time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+		}
+		return time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+	default:
+		return value.String()
+	}
+}
+
+// applyDefaultDateFormat gives a cell that is about to receive a date the default date or date-time number format,
+// unless the template already formats the cell. The rest of the cell's style is kept.
+func (g *Generator) applyDefaultDateFormat(file *excelize.File, sheetName, cell string, value time.Time) error {
+	baseStyleID, err := file.GetCellStyle(sheetName, cell)
+	if err != nil {
+		return fmt.Errorf("get style of cell %q: %w", cell, err)
+	}
+
+	style := &excelize.Style{}
+	if baseStyleID != 0 {
+		if style, err = file.GetStyle(baseStyleID); err != nil {
+			return fmt.Errorf("get style %d: %w", baseStyleID, err)
+		}
+		if style.NumFmt != 0 || style.CustomNumFmt != nil {
+			return nil // The template decides how the date is shown.
+		}
+	}
+
+	numFmt := cmp.Or(g.config.DateTimeFormat, DefaultDateTimeFormat)
+	if isDateOnly(value) {
+		numFmt = cmp.Or(g.config.DateFormat, DefaultDateFormat)
+	}
+
+	key := dateStyleKey{baseStyleID: baseStyleID, numFmt: numFmt}
+	styleID, ok := g.dateStyles[key]
+	if !ok {
+		style.CustomNumFmt = &numFmt
+		if styleID, err = file.NewStyle(style); err != nil {
+			return fmt.Errorf("create date style with number format %q: %w", numFmt, err)
+		}
+		g.dateStyles[key] = styleID
+	}
+
+	if err := file.SetCellStyle(sheetName, cell, cell, styleID); err != nil {
+		return fmt.Errorf("set date style of cell %q: %w", cell, err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package report
+
+import (
+	"log/slog"
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+// decimalCellValue returns the value a decimal is written to a cell as. Excel stores numbers as floats, so the decimal
+// is only written as a number if the float represents it exactly, meaning it reads back as the same decimal. Otherwise
+// it is written as text, keeping all digits, and a warning is logged with the cell.
+func decimalCellValue(d decimal.Decimal, logger *slog.Logger) any {
+	f := d.InexactFloat64()
+	if isExactFloat(d, f) {
+		return f
+	}
+
+	logger.Warn(
+		"Decimal can't be written as a number without losing precision, writing it as text",
+		slog.String("value", d.String()),
+		slog.Float64("nearest_number", f),
+	)
+	return d.String()
+}
+
+// isExactFloat reports whether f is the float nearest to d and prints as the same decimal, e.g. 0.1 for "0.10".
+func isExactFloat(d decimal.Decimal, f float64) bool {
+	return !math.IsInf(f, 0) && decimal.NewFromFloat(f).Equal(d)
+}
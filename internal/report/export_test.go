@@ -0,0 +1,8 @@
+package report
+
+// Exported aliases of unexported functions for the external report_test package.
+var (
+	CoerceTemplateOutput = coerceTemplateOutput
+	ProcessTemplate      = processTemplate
+	ExtendRanges         = extendRanges
+)
@@ -0,0 +1,103 @@
+package report
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrorsSheetName is the sheet failed rows are listed on when generating with OnErrorContinue.
+const ErrorsSheetName = "Excalibur Errors"
+
+// ErrReportIncomplete indicates a report that was written with OnErrorContinue, but with rows whose data couldn't be
+// filled in.
+var ErrReportIncomplete = errors.New("report is incomplete")
+
+// RowFailure is a referenced row whose data couldn't be filled in.
+type RowFailure struct {
+	Sheet   string `json:"sheet"`
+	Row     int    `json:"row"`
+	SQLFile string `json:"sql_file"`
+	Error   string `json:"error"`
+}
+
+// recordFailure records a failed row and writes the error marker into its placeholder cells, so the gap is visible in
+// the report itself.
+func (g *Generator) recordFailure(
+	file *excelize.File,
+	sheetName string,
+	excelRowIndex int,
+	rowCells []string,
+	zeroBasedSQLColIndex int,
+	rowErr error,
+	logger *slog.Logger,
+) {
+	failure := RowFailure{
+		Sheet:   sheetName,
+		Row:     excelRowIndex,
+		SQLFile: parseReference(rowCells[zeroBasedSQLColIndex]).Path,
+		Error:   rowErr.Error(),
+	}
+	g.failures = append(g.failures, failure)
+	logger.Error("Row failed, marking it and continuing", slog.String("error", failure.Error))
+
+	if g.plan != nil {
+		g.plan.Failures = append(g.plan.Failures, failure)
+		return // A dry run never modifies the template.
+	}
+
+	marker := cmp.Or(g.config.ErrorMarker, DefaultErrorMarker)
+	for cellIndex, cellValue := range rowCells {
+		if cellIndex == zeroBasedSQLColIndex || !strings.Contains(cellValue, "{{") {
+			continue
+		}
+		cell, _ := excelize.CoordinatesToCellName(cellIndex+1, excelRowIndex)
+		if err := file.SetCellValue(sheetName, cell, marker); err != nil {
+			logger.Warn("Failed to write error marker", slog.String("cell", cell), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// writeErrorsSheet lists the recorded failures on ErrorsSheetName, replacing a sheet of that name if the template has
+// one.
+func (g *Generator) writeErrorsSheet(file *excelize.File) error {
+	if index, err := file.GetSheetIndex(ErrorsSheetName); err == nil && index >= 0 {
+		if err := file.DeleteSheet(ErrorsSheetName); err != nil {
+			return fmt.Errorf("replace sheet %q: %w", ErrorsSheetName, err)
+		}
+	}
+	if _, err := file.NewSheet(ErrorsSheetName); err != nil {
+		return fmt.Errorf("create sheet %q: %w", ErrorsSheetName, err)
+	}
+
+	rows := [][]any{{"Sheet", "Row", "SQL File", "Error"}}
+	for _, failure := range g.failures {
+		rows = append(rows, []any{failure.Sheet, failure.Row, failure.SQLFile, failure.Error})
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := file.SetSheetRow(ErrorsSheetName, cell, &row); err != nil {
+			return fmt.Errorf("write row %d of sheet %q: %w", i+1, ErrorsSheetName, err)
+		}
+	}
+
+	return nil
+}
+
+// Incomplete reports whether rows failed so far, see OnErrorContinue. Once Generate starts writing the workbook, it
+// reports whether the workbook is incomplete, e.g. to announce that before passing it on.
+func (g *Generator) Incomplete() bool {
+	return len(g.failures) > 0
+}
+
+// incompleteError returns an ErrReportIncomplete error summarizing the recorded failures, or nil if there are none.
+func (g *Generator) incompleteError() error {
+	if !g.Incomplete() {
+		return nil
+	}
+	return fmt.Errorf("%w: %d row(s) failed, see sheet %q", ErrReportIncomplete, len(g.failures), ErrorsSheetName)
+}
@@ -0,0 +1,117 @@
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// formulaRangeRegex matches cell ranges in formulas, optionally qualified with a sheet name, e.g. "B2:B7", "$B$2:$B$2"
+// or "'Sales 2026'!B2:B7".
+var formulaRangeRegex = regexp.MustCompile(
+	`((?:'(?:[^']|'')+'|[A-Za-z_][A-Za-z0-9_.]*)!)?(\$?[A-Za-z]{1,3}\$?)(\d+):(\$?[A-Za-z]{1,3}\$?)(\d+)`,
+)
+
+// Indexes of the capture groups of formulaRangeRegex.
+const (
+	formulaRangeSheetIndex    = 1
+	formulaRangeStartColIndex = 2
+	formulaRangeStartRowIndex = 3
+	formulaRangeEndColIndex   = 4
+	formulaRangeEndRowIndex   = 5
+)
+
+// extendFormulaRanges extends cell ranges in the formulas of the workbook that end at the prototype row of an expanded
+// table by the rows inserted below it, e.g. the SUM(B2:B2) of a totals row to SUM(B2:B4) for three records. Inserting
+// rows only moves references below the insertion point, so such ranges would otherwise only cover the first record.
+// The formulas of the table rows themselves were copied from the prototype and are left alone.
+func extendFormulaRanges(file *excelize.File, sheetName string, prototypeRow, insertedRows int) error {
+	for _, formulaSheet := range file.GetSheetList() {
+		lastCol, lastRow, err := sheetExtent(file, formulaSheet)
+		if err != nil {
+			return err
+		}
+
+		for row := 1; row <= lastRow; row++ {
+			if formulaSheet == sheetName && row >= prototypeRow && row <= prototypeRow+insertedRows {
+				continue
+			}
+			for col := 1; col <= lastCol; col++ {
+				cell, _ := excelize.CoordinatesToCellName(col, row)
+				formula, err := file.GetCellFormula(formulaSheet, cell)
+				if err != nil {
+					return fmt.Errorf("get formula of cell %s!%s: %w", formulaSheet, cell, err)
+				}
+				if formula == "" {
+					continue
+				}
+
+				extended := extendRanges(formula, formulaSheet == sheetName, sheetName, prototypeRow, insertedRows)
+				if extended == formula {
+					continue
+				}
+				if err := file.SetCellFormula(formulaSheet, cell, extended); err != nil {
+					return fmt.Errorf("set formula of cell %s!%s: %w", formulaSheet, cell, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// extendRanges returns the formula with the end row of every range on sheetName that ends at prototypeRow moved down by
+// insertedRows. Unqualified ranges refer to sheetName if onSheet is set.
+func extendRanges(formula string, onSheet bool, sheetName string, prototypeRow, insertedRows int) string {
+	return formulaRangeRegex.ReplaceAllStringFunc(formula, func(ref string) string {
+		groups := formulaRangeRegex.FindStringSubmatch(ref)
+		if qualifier := groups[formulaRangeSheetIndex]; qualifier != "" {
+			name := strings.TrimSuffix(qualifier, "!")
+			if unquoted, found := strings.CutPrefix(name, "'"); found {
+				name = strings.ReplaceAll(strings.TrimSuffix(unquoted, "'"), "''", "'")
+			}
+			if !strings.EqualFold(name, sheetName) {
+				return ref
+			}
+		} else if !onSheet {
+			return ref
+		}
+
+		startRow, _ := strconv.Atoi(groups[formulaRangeStartRowIndex])
+		endRow, _ := strconv.Atoi(groups[formulaRangeEndRowIndex])
+		if endRow != prototypeRow || startRow > prototypeRow {
+			return ref
+		}
+		return groups[formulaRangeSheetIndex] + groups[formulaRangeStartColIndex] + groups[formulaRangeStartRowIndex] +
+			":" + groups[formulaRangeEndColIndex] + strconv.Itoa(endRow+insertedRows)
+	})
+}
+
+// sheetExtent returns the last column and row of a sheet that may hold a cell, from its recorded dimension and its
+// rows. Either alone may miss cells: the dimension may be missing or outdated, and rows omit trailing cells without a
+// value, such as formulas that were never calculated.
+func sheetExtent(file *excelize.File, sheetName string) (int, int, error) {
+	rows, err := file.GetRows(sheetName)
+	if err != nil {
+		return 0, 0, fmt.Errorf("get rows from sheet %q: %w", sheetName, err)
+	}
+	lastCol, lastRow := 0, len(rows)
+	for _, row := range rows {
+		lastCol = max(lastCol, len(row))
+	}
+
+	dimension, err := file.GetSheetDimension(sheetName)
+	if err != nil {
+		return 0, 0, fmt.Errorf("get dimension of sheet %q: %w", sheetName, err)
+	}
+	if _, end, found := strings.Cut(dimension, ":"); found {
+		if col, row, err := excelize.CellNameToCoordinates(end); err == nil {
+			lastCol, lastRow = max(lastCol, col), max(lastRow, row)
+		}
+	}
+
+	return lastCol, lastRow, nil
+}
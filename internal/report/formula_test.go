@@ -0,0 +1,38 @@
+package report_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+func TestExtendRanges(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		formula  string
+		onSheet  bool
+		expected string
+	}{
+		{name: "Ending At Prototype", formula: "SUM(B2:B2)", onSheet: true, expected: "SUM(B2:B5)"},
+		{name: "Starting Above", formula: "COUNTA(A1:C2)", onSheet: true, expected: "COUNTA(A1:C5)"},
+		{name: "Absolute", formula: "SUM($B$2:$B$2)", onSheet: true, expected: "SUM($B$2:$B$5)"},
+		{name: "Ending Elsewhere", formula: "SUM(B1:B1)+SUM(B2:B3)", onSheet: true, expected: "SUM(B1:B1)+SUM(B2:B3)"},
+		{name: "Single Cell", formula: "B2*2", onSheet: true, expected: "B2*2"},
+		{name: "Unqualified On Other Sheet", formula: "SUM(B2:B2)", expected: "SUM(B2:B2)"},
+		{name: "Qualified", formula: "SUM(Data!B2:B2)", expected: "SUM(Data!B2:B5)"},
+		{name: "Quoted", formula: "SUM('data'!B2:B2)", expected: "SUM('data'!B2:B5)"},
+		{name: "Qualified With Other Sheet", formula: "SUM(Other!B2:B2)", onSheet: true, expected: "SUM(Other!B2:B2)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, report.ExtendRanges(tc.formula, tc.onSheet, "Data", 2, 3))
+		})
+	}
+}
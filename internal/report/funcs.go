@@ -0,0 +1,545 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// defaultNumberLocale is the locale of formatNumber and percent.
+const defaultNumberLocale = "en"
+
+// ErrInvalidFuncArg indicates a template function called with a value it can't handle.
+var ErrInvalidFuncArg = errors.New("invalid template function argument")
+
+// templateFuncs returns the functions available in cell templates. The value a function operates on is always its last
+// argument, so that it can be piped, e.g. `{{ .price | formatNumber 2 }}`. NULL values (nil) never cause an error:
+// formatting functions render them as an empty string, functions returning a value return nil, which cell templates
+// print as an empty string too, and default and coalesce exist to replace them. Exact decimals stay exact through arithmetic, rounding and formatting; the decimal
+// function turns other numbers into one.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// Numbers
+		"formatNumber":       formatNumber,
+		"formatNumberLocale": formatNumberLocale,
+		"round":              round,
+		"percent":            percent,
+		"decimal":            toDecimalFunc,
+
+		// Dates
+		"date":      formatDate,
+		"addDays":   addDays,
+		"monthName": monthName,
+
+		// NULL handling
+		"default":  defaultValue,
+		"coalesce": coalesce,
+
+		// Strings
+		"upper":    stringFunc(strings.ToUpper),
+		"lower":    stringFunc(strings.ToLower),
+		"title":    stringFunc(cases.Title(language.Und).String),
+		"truncate": truncate,
+
+		// Arrays and objects
+		"join":  join,
+		"index": index,
+		"len":   length,
+
+		// Safe math
+		"add": add,
+		"sub": sub,
+		"mul": mul,
+		"div": div,
+	}
+}
+
+// formatNumber formats a number with the given number of decimals and the separators of the default locale, e.g.
+// "1,234.50".
+func formatNumber(decimals int, value any) (string, error) {
+	return formatNumberLocale(defaultNumberLocale, decimals, value)
+}
+
+// formatNumberLocale formats a number with the given number of decimals and the separators of a BCP 47 locale, e.g.
+// "1.234,50" for "de".
+func formatNumberLocale(locale string, decimals int, value any) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", fmt.Errorf("%w: locale %q: %w", ErrInvalidFuncArg, locale, err)
+	}
+	if d, ok := value.(decimal.Decimal); ok {
+		return formatDecimal(message.NewPrinter(tag), max(decimals, 0), d), nil
+	}
+	f, err := toFloat(value)
+	if err != nil {
+		return "", err
+	}
+	return message.NewPrinter(tag).Sprintf("%.*f", max(decimals, 0), f), nil
+}
+
+// round rounds a number half away from zero to the given number of decimal places.
+func round(places int, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if d, ok := value.(decimal.Decimal); ok {
+		return d.Round(int32(places)), nil //nolint:gosec // Decimal places beyond int32 are meaningless.
+	}
+	f, err := toFloat(value)
+	if err != nil {
+		return nil, err
+	}
+	scale := math.Pow10(places)
+	return math.Round(f*scale) / scale, nil
+}
+
+// percent formats a ratio as a percentage with the given number of decimals, e.g. 0.125 as "12.5%".
+func percent(decimals int, value any) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	if d, ok := value.(decimal.Decimal); ok {
+		formatted, err := formatNumber(decimals, d.Shift(2))
+		return formatted + "%", err
+	}
+	f, err := toFloat(value)
+	if err != nil {
+		return "", err
+	}
+	formatted, err := formatNumber(decimals, f*100)
+	if err != nil {
+		return "", err
+	}
+	return formatted + "%", nil
+}
+
+// toDecimalFunc converts a number, or a string holding one, into an exact decimal, e.g. to sum up prices without float
+// rounding errors: `{{ add (decimal .net) (decimal .tax) }}`.
+func toDecimalFunc(value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return toDecimal(value)
+}
+
+// formatDate formats a date with a Go layout, e.g. `{{ .day | date "02.01.2006" }}`.
+func formatDate(layout string, value any) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	t, err := toTime(value)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(layout), nil
+}
+
+// addDays adds a number of days, which may be negative, to a date.
+func addDays(days int, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	t, err := toTime(value)
+	if err != nil {
+		return nil, err
+	}
+	return t.AddDate(0, 0, days), nil
+}
+
+// monthName returns the English name of a date's month, or of a month number from 1 to 12.
+func monthName(value any) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	if t, err := toTime(value); err == nil {
+		return t.Month().String(), nil
+	}
+
+	f, err := toFloat(value)
+	if err != nil || f != math.Trunc(f) || f < 1 || f > 12 {
+		return "", fmt.Errorf("%w: %v is neither a date nor a month number", ErrInvalidFuncArg, value)
+	}
+	return time.Month(int(f)).String(), nil
+}
+
+// defaultValue returns value, or fallback if value is NULL or an empty string.
+func defaultValue(fallback, value any) any {
+	if isEmpty(value) {
+		return fallback
+	}
+	return value
+}
+
+// coalesce returns the first value that is neither NULL nor an empty string, or nil if there is none.
+func coalesce(values ...any) any {
+	for _, value := range values {
+		if !isEmpty(value) {
+			return value
+		}
+	}
+	return nil
+}
+
+// stringFunc turns a string transformation into a template function that renders NULL as an empty string and other
+// values the way templates print them.
+func stringFunc(transform func(string) string) func(any) string {
+	return func(value any) string {
+		if value == nil {
+			return ""
+		}
+		return transform(fmt.Sprint(value))
+	}
+}
+
+// truncate shortens a value's text to at most length characters.
+func truncate(length int, value any) string {
+	if value == nil {
+		return ""
+	}
+	s := fmt.Sprint(value)
+	if length < 0 || utf8.RuneCountInString(s) <= length {
+		return s
+	}
+	return string([]rune(s)[:length])
+}
+
+// join renders the elements of an array separated by sep, e.g. `{{ .tags | join ", " }}`. Objects are rendered as
+// "key: value" pairs.
+func join(sep string, value any) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	return joinCollection(reflect.ValueOf(value), sep)
+}
+
+// index returns the element of an array at a position, or the field of an object with a name, following several keys
+// through nested collections, e.g. `{{ index .settings "limits" "daily" }}`. Unlike the built-in function it replaces,
+// it returns nil for NULL collections, missing fields and positions out of range.
+func index(collection any, keys ...any) (any, error) {
+	value := collection
+	for _, key := range keys {
+		if value == nil {
+			return nil, nil
+		}
+
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			i, ok := toInt(key)
+			if !ok {
+				return nil, fmt.Errorf("%w: array position %v (%T) is not an integer", ErrInvalidFuncArg, key, key)
+			}
+			if i < 0 || i >= int64(rv.Len()) {
+				return nil, nil
+			}
+			value = rv.Index(int(i)).Interface()
+		case reflect.Map:
+			if rv.Type().Key().Kind() != reflect.String {
+				return nil, fmt.Errorf("%w: %T has no named fields", ErrInvalidFuncArg, value)
+			}
+			field := rv.MapIndex(reflect.ValueOf(fmt.Sprint(key)).Convert(rv.Type().Key()))
+			if !field.IsValid() {
+				return nil, nil
+			}
+			value = field.Interface()
+		default:
+			return nil, fmt.Errorf("%w: %v (%T) is not an array or object", ErrInvalidFuncArg, value, value)
+		}
+	}
+	return value, nil
+}
+
+// length returns the number of elements of an array or object, or of characters of a string. NULL has length 0.
+func length(value any) (int, error) {
+	if value == nil {
+		return 0, nil
+	}
+	if s, ok := value.(string); ok {
+		return utf8.RuneCountInString(s), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len(), nil
+	default:
+		return 0, fmt.Errorf("%w: %v (%T) has no length", ErrInvalidFuncArg, value, value)
+	}
+}
+
+// add returns a + b, or nil if either is NULL.
+func add(a, b any) (any, error) {
+	return arithmetic(a, b, func(x, y int64) (int64, bool) {
+		sum := x + y
+		return sum, (sum > x) == (y > 0)
+	}, func(x, y float64) float64 { return x + y }, decimal.Decimal.Add)
+}
+
+// sub returns a - b, or nil if either is NULL.
+func sub(a, b any) (any, error) {
+	return arithmetic(a, b, func(x, y int64) (int64, bool) {
+		diff := x - y
+		return diff, (diff < x) == (y > 0)
+	}, func(x, y float64) float64 { return x - y }, decimal.Decimal.Sub)
+}
+
+// mul returns a * b, or nil if either is NULL.
+func mul(a, b any) (any, error) {
+	return arithmetic(a, b, func(x, y int64) (int64, bool) {
+		if x == 0 || y == 0 {
+			return 0, true
+		}
+		if (x == -1 && y == math.MinInt64) || (y == -1 && x == math.MinInt64) {
+			return 0, false
+		}
+		product := x * y
+		return product, product/y == x
+	}, func(x, y float64) float64 { return x * y }, decimal.Decimal.Mul)
+}
+
+// div returns a / b as a float, or as a decimal if either is one, or nil if either is NULL or b is zero.
+func div(a, b any) (any, error) {
+	if a == nil || b == nil {
+		return nil, nil
+	}
+	if isDecimal(a) || isDecimal(b) {
+		x, y, err := toDecimals(a, b)
+		if err != nil || y.IsZero() {
+			return nil, err
+		}
+		return x.Div(y), nil
+	}
+	x, err := toFloat(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := toFloat(b)
+	if err != nil {
+		return nil, err
+	}
+	if y == 0 {
+		return nil, nil
+	}
+	return x / y, nil
+}
+
+// arithmetic applies an operation to two numbers. If either is a decimal, both are treated as decimals. Integers stay
+// integers unless the result overflows, in which case the operation is repeated on floats. NULL operands yield nil.
+func arithmetic(
+	a, b any,
+	intOp func(x, y int64) (int64, bool),
+	floatOp func(x, y float64) float64,
+	decimalOp func(x, y decimal.Decimal) decimal.Decimal,
+) (any, error) {
+	if a == nil || b == nil {
+		return nil, nil
+	}
+
+	if isDecimal(a) || isDecimal(b) {
+		x, y, err := toDecimals(a, b)
+		if err != nil {
+			return nil, err
+		}
+		return decimalOp(x, y), nil
+	}
+
+	x, xIsInt := toInt(a)
+	y, yIsInt := toInt(b)
+	if xIsInt && yIsInt {
+		if result, ok := intOp(x, y); ok {
+			return result, nil
+		}
+	}
+
+	fx, err := toFloat(a)
+	if err != nil {
+		return nil, err
+	}
+	fy, err := toFloat(b)
+	if err != nil {
+		return nil, err
+	}
+	return floatOp(fx, fy), nil
+}
+
+// isEmpty reports whether a value is NULL or an empty string.
+func isEmpty(value any) bool {
+	if value == nil {
+		return true
+	}
+	s, ok := value.(string)
+	return ok && s == ""
+}
+
+// toInt converts signed and small unsigned integers to int64.
+func toInt(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// toFloat converts numbers, and strings holding a number, to float64.
+func toFloat(value any) (float64, error) {
+	if i, ok := toInt(value); ok {
+		return float64(i), nil
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case decimal.Decimal:
+		return v.InexactFloat64(), nil
+	case float32:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q is not a number", ErrInvalidFuncArg, v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("%w: %v (%T) is not a number", ErrInvalidFuncArg, value, value)
+	}
+}
+
+// toTime converts dates, and strings holding a date in one of the layouts template output is coerced from, to
+// time.Time.
+func toTime(value any) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range coercionDateLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%w: %q is not a date", ErrInvalidFuncArg, v)
+	default:
+		return time.Time{}, fmt.Errorf("%w: %v (%T) is not a date", ErrInvalidFuncArg, value, value)
+	}
+}
+
+// isDecimal reports whether value is an exact decimal.
+func isDecimal(value any) bool {
+	_, ok := value.(decimal.Decimal)
+	return ok
+}
+
+// toDecimal converts numbers, and strings holding a number, to an exact decimal. Floats are converted to the shortest
+// decimal that reads back as the same float, e.g. 0.1 to exactly 0.1.
+func toDecimal(value any) (decimal.Decimal, error) {
+	if i, ok := toInt(value); ok {
+		return decimal.NewFromInt(i), nil
+	}
+
+	switch v := value.(type) {
+	case decimal.Decimal:
+		return v, nil
+	case string:
+		d, err := decimal.NewFromString(strings.TrimSpace(v))
+		if err != nil {
+			return decimal.Decimal{}, fmt.Errorf("%w: %q is not a number", ErrInvalidFuncArg, v)
+		}
+		return d, nil
+	default:
+		f, err := toFloat(value)
+		if err != nil {
+			return decimal.Decimal{}, err
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return decimal.Decimal{}, fmt.Errorf("%w: %v has no decimal representation", ErrInvalidFuncArg, f)
+		}
+		return decimal.NewFromFloat(f), nil
+	}
+}
+
+// toDecimals converts both operands of an operation to exact decimals.
+func toDecimals(a, b any) (decimal.Decimal, decimal.Decimal, error) {
+	x, err := toDecimal(a)
+	if err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, err
+	}
+	y, err := toDecimal(b)
+	if err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, err
+	}
+	return x, y, nil
+}
+
+// formatDecimal formats a decimal with the given number of decimals without converting it to a float. The separators
+// are taken from how the printer formats a float, so they match formatNumberLocale for other numbers; the digits are
+// grouped by thousands.
+func formatDecimal(printer *message.Printer, decimals int, d decimal.Decimal) string {
+	groupSep, decimalSep, ok := localeSeparators(printer)
+	fixed := d.StringFixed(int32(decimals)) //nolint:gosec // Decimal places beyond int32 are meaningless.
+	if !ok {
+		return fixed
+	}
+
+	sign, digits := "", fixed
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(digits, ".")
+
+	var sb strings.Builder
+	sb.WriteString(sign)
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			sb.WriteString(groupSep)
+		}
+		sb.WriteRune(digit)
+	}
+	if hasFraction {
+		sb.WriteString(decimalSep)
+		sb.WriteString(fraction)
+	}
+	return sb.String()
+}
+
+// localeSeparators returns the group and decimal separators of the printer's locale. It reports false for locales that
+// don't use ASCII digits, for which decimals are formatted without separators.
+func localeSeparators(printer *message.Printer) (string, string, bool) {
+	sample := printer.Sprintf("%.1f", 1000.5)
+	groupSep, rest, ok := strings.Cut(strings.TrimPrefix(sample, "1"), "000")
+	if !ok || !strings.HasPrefix(sample, "1") || !strings.HasSuffix(rest, "5") {
+		return "", "", false
+	}
+	return groupSep, strings.TrimSuffix(rest, "5"), true
+}
@@ -0,0 +1,209 @@
+package report_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]any{
+		"amount":   1234.5,
+		"count":    int64(7),
+		"zero":     int64(0),
+		"ratio":    0.125,
+		"text":     "hello world",
+		"umlauts":  "äöü straße",
+		"empty":    "",
+		"numeric":  "2.5",
+		"day":      time.Date(2026, 10, 16, 8, 30, 0, 0, time.UTC),
+		"dayText":  "2026-02-28",
+		"month":    int64(3),
+		"null":     nil,
+		"maxInt":   int64(9223372036854775807),
+		"fraction": 2.675,
+		"tags":     []any{"alpha", nil, int64(3)},
+		"settings": map[string]any{"level": 10.0, "mode": "fast", "limits": map[string]any{"daily": 5.0}},
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		// formatNumber, formatNumberLocale
+		{name: "formatNumber", template: `{{ .amount | formatNumber 2 }}`, expected: "1,234.50"},
+		{name: "formatNumber Integer", template: `{{ .count | formatNumber 0 }}`, expected: "7"},
+		{name: "formatNumber String", template: `{{ .numeric | formatNumber 1 }}`, expected: "2.5"},
+		{name: "formatNumber NULL", template: `{{ .null | formatNumber 2 }}`, expected: ""},
+		{name: "formatNumberLocale German", template: `{{ .amount | formatNumberLocale "de" 2 }}`, expected: "1.234,50"},
+		{name: "formatNumberLocale NULL", template: `{{ .null | formatNumberLocale "de" 2 }}`, expected: ""},
+
+		// round
+		{name: "round", template: `{{ .fraction | round 1 }}`, expected: "2.7"},
+		{name: "round Integer", template: `{{ .count | round 0 }}`, expected: "7"},
+		{name: "round NULL", template: `{{ .null | round 2 }}`, expected: ""},
+
+		// percent
+		{name: "percent", template: `{{ .ratio | percent 1 }}`, expected: "12.5%"},
+		{name: "percent NULL", template: `{{ .null | percent 1 }}`, expected: ""},
+
+		// date, addDays, monthName
+		{name: "date", template: `{{ .day | date "02.01.2006 15:04" }}`, expected: "16.10.2026 08:30"},
+		{name: "date String", template: `{{ .dayText | date "Jan 2" }}`, expected: "Feb 28"},
+		{name: "date NULL", template: `{{ .null | date "2006" }}`, expected: ""},
+		{name: "addDays", template: `{{ .dayText | addDays 1 | date "2006-01-02" }}`, expected: "2026-03-01"},
+		{name: "addDays Negative", template: `{{ .day | addDays -16 | date "2006-01-02" }}`, expected: "2026-09-30"},
+		{name: "addDays NULL", template: `{{ .null | addDays 1 | date "2006-01-02" }}`, expected: ""},
+		{name: "monthName", template: `{{ .day | monthName }}`, expected: "October"},
+		{name: "monthName Number", template: `{{ .month | monthName }}`, expected: "March"},
+		{name: "monthName NULL", template: `{{ .null | monthName }}`, expected: ""},
+
+		// default, coalesce
+		{name: "default", template: `{{ .text | default "n/a" }}`, expected: "hello world"},
+		{name: "default Empty", template: `{{ .empty | default "n/a" }}`, expected: "n/a"},
+		{name: "default NULL", template: `{{ .null | default "n/a" }}`, expected: "n/a"},
+		{name: "default Zero", template: `{{ .zero | default 1 }}`, expected: "0"},
+		{name: "coalesce", template: `{{ coalesce .null .empty .count .text }}`, expected: "7"},
+		{name: "coalesce NULL", template: `{{ coalesce .null .empty }}`, expected: ""},
+
+		// upper, lower, title, truncate
+		{name: "upper", template: `{{ .umlauts | upper }}`, expected: "ÄÖÜ STRAßE"},
+		{name: "upper NULL", template: `{{ .null | upper }}`, expected: ""},
+		{name: "lower", template: `{{ "MiXeD" | lower }}`, expected: "mixed"},
+		{name: "lower NULL", template: `{{ .null | lower }}`, expected: ""},
+		{name: "title", template: `{{ .text | title }}`, expected: "Hello World"},
+		{name: "title NULL", template: `{{ .null | title }}`, expected: ""},
+		{name: "truncate", template: `{{ .umlauts | truncate 3 }}`, expected: "äöü"},
+		{name: "truncate Short", template: `{{ .text | truncate 50 }}`, expected: "hello world"},
+		{name: "truncate NULL", template: `{{ .null | truncate 3 }}`, expected: ""},
+
+		// add, sub, mul, div
+		{name: "add", template: `{{ add .count 3 }}`, expected: "10"},
+		{name: "add Float", template: `{{ add .count .amount }}`, expected: "1241.5"},
+		{name: "add Overflow", template: `{{ add .maxInt 1 }}`, expected: "9.223372036854776e+18"},
+		{name: "add NULL", template: `{{ add .null 3 }}`, expected: ""},
+		{name: "sub", template: `{{ sub .count 10 }}`, expected: "-3"},
+		{name: "sub NULL", template: `{{ sub .count .null }}`, expected: ""},
+		{name: "mul", template: `{{ mul .count .numeric }}`, expected: "17.5"},
+		{name: "mul Overflow", template: `{{ mul .maxInt 2 }}`, expected: "1.8446744073709552e+19"},
+		{name: "mul NULL", template: `{{ mul .null .null }}`, expected: ""},
+		{name: "div", template: `{{ div .count 2 }}`, expected: "3.5"},
+		{name: "div By Zero", template: `{{ div .count .zero | default "-" }}`, expected: "-"},
+		{name: "div NULL", template: `{{ div .null 2 }}`, expected: ""},
+
+		// join, index, len
+		{name: "join", template: `{{ .tags | join ", " }}`, expected: "alpha, , 3"},
+		{
+			name:     "join Object",
+			template: `{{ .settings | join "; " }}`,
+			expected: `level: 10; limits: {"daily":5}; mode: fast`,
+		},
+		{name: "join NULL", template: `{{ .null | join ", " }}`, expected: ""},
+		{name: "index", template: `{{ index .tags 0 }}`, expected: "alpha"},
+		{name: "index Out Of Range", template: `{{ index .tags 5 | default "-" }}`, expected: "-"},
+		{name: "index Nested", template: `{{ index .settings "limits" "daily" }}`, expected: "5"},
+		{name: "index Missing Field", template: `{{ index .settings "unknown" | default "-" }}`, expected: "-"},
+		{name: "index NULL", template: `{{ index .null 0 | default "-" }}`, expected: "-"},
+		{name: "len", template: `{{ len .tags }}`, expected: "3"},
+		{name: "len Object", template: `{{ len .settings }}`, expected: "3"},
+		{name: "len String", template: `{{ len .umlauts }}`, expected: "10"},
+		{name: "len NULL", template: `{{ len .null }}`, expected: "0"},
+		{name: "Nested Field", template: `{{ .settings.limits.daily }} per day`, expected: "5 per day"},
+
+		// NULL results print as nothing, wherever they appear.
+		{name: "NULL Field In Text", template: `Total: {{ .null }}`, expected: "Total: "},
+		{name: "NULL In Condition", template: `{{ if .count }}[{{ add .null 1 }}]{{ end }}`, expected: "[]"},
+		{name: "NULL In Range", template: `{{ range .tags }}{{ . }};{{ end }}`, expected: "alpha;;3;"},
+		{name: "NULL Assigned", template: `{{ $x := .null }}{{ $x | default "-" }}`, expected: "-"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := report.ProcessTemplate(tc.template, data, false)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTemplateFuncs_Decimals(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]any{
+		"big":   decimal.RequireFromString("12345678901234567890.125"),
+		"price": decimal.RequireFromString("0.10"),
+		"tax":   decimal.RequireFromString("-1234.5"),
+		"ratio": decimal.RequireFromString("0.1255"),
+		"count": int64(3),
+		"float": 0.2,
+		"zero":  decimal.Zero,
+		"text":  "0.30",
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{name: "Printed Exactly", template: `{{ .big }} EUR`, expected: "12345678901234567890.125 EUR"},
+		{name: "decimal", template: `{{ add (decimal .float) (decimal .text) }}`, expected: "0.5"},
+		{name: "add", template: `{{ add .price .float }}`, expected: "0.3"},
+		{name: "add Large", template: `{{ add .big 1 }}`, expected: "12345678901234567891.125"},
+		{name: "sub", template: `{{ sub .price .text }}`, expected: "-0.2"},
+		{name: "mul", template: `{{ mul .price .count }}`, expected: "0.3"},
+		{name: "div", template: `{{ div .tax .count }}`, expected: "-411.5"},
+		{name: "div By Zero", template: `{{ div .tax .zero | default "-" }}`, expected: "-"},
+		{name: "round", template: `{{ .big | round 2 }}`, expected: "12345678901234567890.13"},
+		{name: "round Negative", template: `{{ .tax | round 0 }}`, expected: "-1235"},
+		{name: "formatNumber", template: `{{ .big | formatNumber 2 }}`, expected: "12,345,678,901,234,567,890.13"},
+		{name: "formatNumber Negative", template: `{{ .tax | formatNumber 2 }}`, expected: "-1,234.50"},
+		{name: "formatNumberLocale", template: `{{ .tax | formatNumberLocale "de" 1 }}`, expected: "-1.234,5"},
+		{name: "percent", template: `{{ .ratio | percent 1 }}`, expected: "12.6%"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := report.ProcessTemplate(tc.template, data, false)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTemplateFuncs_InvalidArguments(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]any{"text": "abc", "day": time.Now(), "month": int64(13)}
+
+	for _, tmpl := range []string{
+		`{{ .text | formatNumber 2 }}`,
+		`{{ .day | round 2 }}`,
+		`{{ .text | date "2006" }}`,
+		`{{ .month | monthName }}`,
+		`{{ add .text 1 }}`,
+		`{{ .text | decimal }}`,
+		`{{ 1 | formatNumberLocale "not a locale!" 2 }}`,
+		`{{ .text | join ", " }}`,
+		`{{ index .text 0 }}`,
+		`{{ len .month }}`,
+	} {
+		t.Run(tmpl, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := report.ProcessTemplate(tmpl, data, false)
+			require.ErrorIs(t, err, report.ErrInvalidFuncArg)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -14,42 +15,71 @@ import (
 	"regexp"
 	"strings"
 	"text/template"
+	"text/template/parse"
+	"time"
 
 	"github.com/nikoksr/assert-go"
+	"github.com/shopspring/decimal"
 	"github.com/xuri/excelize/v2"
 
 	"github.com/nikoksr/excalibur/internal/datasource"
 )
 
 type Generator struct {
-	dataSource datasource.DataSource
-	config     Config
-	logger     *slog.Logger
+	sources *datasource.Registry
+	config  Config
+	logger  *slog.Logger
+	plan    *Plan // Records what the generator does; only set during a dry run.
+	queryFS fs.FS // SQL files, by their path in the reference column; QueriesDir unless set with SetQueries.
+
+	failures []RowFailure // Rows that failed with OnErrorContinue, in processing order.
+
+	queryFiles map[string][]byte            // Contents of the SQL files read in the current run, by absolute path.
+	queries    map[string]*pendingQuery     // Queries of the current run by queryCacheKey; identical queries run once.
+	pending    map[pendingKey]*pendingQuery // Queries started ahead of their rows; only set with a Concurrency above one.
+	diskCache  *diskCache                   // Persists query results across runs; nil unless CacheTTL is set.
+
+	dateStyles map[dateStyleKey]int // Styles created for date cells without a number format, by base style.
 }
 
-func NewGenerator(source datasource.DataSource, cfg Config, logger *slog.Logger) *Generator {
-	assert.Assert(source != nil, "DataSource must not be nil")
+func NewGenerator(sources *datasource.Registry, cfg Config, logger *slog.Logger) *Generator {
+	assert.Assert(sources != nil, "data source registry must not be nil")
 	assert.Assert(logger != nil, "Logger must not be nil")
-	assert.Assert(filepath.IsAbs(cfg.TemplatePath), "template path must be absolute")
-	assert.Assert(filepath.IsAbs(cfg.OutputPath), "output path must be absolute")
-	assert.Assert(filepath.IsAbs(cfg.QueriesDir), "queries directory must be absolute")
+	// Paths are only needed by the file based API; Generate works with readers and writers alone.
+	assert.Assert(cfg.TemplatePath == "" || filepath.IsAbs(cfg.TemplatePath), "template path must be absolute")
+	assert.Assert(cfg.OutputPath == "" || filepath.IsAbs(cfg.OutputPath), "output path must be absolute")
+	assert.Assert(cfg.QueriesDir == "" || filepath.IsAbs(cfg.QueriesDir), "queries directory must be absolute")
 
 	logger = logger.With(slog.String("component", "ReportGenerator"))
 
-	return &Generator{
-		dataSource: source,
+	g := &Generator{
+		sources:    sources,
 		config:     cfg,
 		logger:     logger,
+		dateStyles: make(map[dateStyleKey]int),
+	}
+	if cfg.QueriesDir != "" {
+		g.queryFS = os.DirFS(cfg.QueriesDir)
 	}
+	if cfg.CacheTTL > 0 {
+		g.diskCache = newDiskCache(cfg.CacheDir, cfg.CacheTTL, logger)
+	}
+	return g
+}
+
+// SetQueries makes the generator read SQL files from fsys instead of QueriesDir, e.g. from an embed.FS. Paths in the
+// reference column are resolved against the root of fsys.
+func (g *Generator) SetQueries(fsys fs.FS) {
+	assert.Assert(fsys != nil, "queries file system must not be nil")
+	g.queryFS = fsys
 }
 
-// GenerateReport orchestrates the report generation:
-// 1. Copies the template to the output path.
-// 2. Opens the copied file.
-// 3. Processes each sheet, looking for SQL references in rows.
-// 4. Fetches data and replaces placeholders.
-// 5. Saves the modified file.
-// Respects context for cancellation/timeouts.
+// GenerateReport generates the report from the template at TemplatePath and writes it to OutputPath, creating its
+// directory if needed. The report is written to a temporary file next to OutputPath, synced and renamed over
+// OutputPath only once generation succeeded, so a failed run never leaves a half-written report and keeps a previous
+// one intact. With KeepFailed, the partially generated workbook of a failed run is kept at FailedOutputPath. With
+// OnErrorContinue, failed rows don't abort generation; they are listed on ErrorsSheetName and the saved report is
+// reported with an ErrReportIncomplete error.
 func (g *Generator) GenerateReport(ctx context.Context) error {
 	g.logger.Info(
 		"Starting report generation process",
@@ -59,49 +89,199 @@ func (g *Generator) GenerateReport(ctx context.Context) error {
 		slog.String("ref_column", g.config.DataSourceRefColumn),
 	)
 
-	// 1. Copy Template File -> Output Path
-	g.logger.Debug(
-		"Copying template file",
-		slog.String("from", g.config.TemplatePath),
-		slog.String("to", g.config.OutputPath),
-	)
-	if err := copyFile(g.config.TemplatePath, g.config.OutputPath); err != nil {
+	templateFile, err := os.Open(g.config.TemplatePath)
+	if err != nil {
+		g.logger.Error("Failed to open template file", slog.String("error", err.Error()))
+		return fmt.Errorf("open template file %q: %w", g.config.TemplatePath, err)
+	}
+	defer templateFile.Close()
+
+	info, err := templateFile.Stat()
+	if err != nil {
+		return fmt.Errorf("stat template file %q: %w", g.config.TemplatePath, err)
+	}
+
+	f, err := g.openWorkbook(templateFile)
+	if err != nil {
+		return err
+	}
+	defer g.closeWorkbook(f)
+
+	if err := g.fill(ctx, f); err != nil {
+		if g.config.KeepFailed {
+			g.keepFailed(f, info.Mode().Perm())
+		}
+		return err
+	}
+
+	g.logger.Info("Saving generated report...", slog.String("path", g.config.OutputPath))
+	if err := writeFileAtomic(g.config.OutputPath, info.Mode().Perm(), workbookWriter(f)); err != nil {
 		g.logger.Error(
-			"Failed to copy template file",
-			slog.String("from", g.config.TemplatePath),
-			slog.String("to", g.config.OutputPath),
+			"Failed to save the generated report file",
+			slog.String("path", g.config.OutputPath),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("copy template file from %q to %q: %w", g.config.TemplatePath, g.config.OutputPath, err)
+		return fmt.Errorf("save generated report file %q: %w", g.config.OutputPath, err)
 	}
-	g.logger.Debug("Template file copied successfully")
 
-	// 2. Open the copied file for modification
-	g.logger.Debug("Opening copied report file for editing", slog.String("path", g.config.OutputPath))
-	f, err := excelize.OpenFile(g.config.OutputPath)
+	return g.incompleteError()
+}
+
+// GenerateFS is like Generate, reading the template from the file name in fsys.
+func (g *Generator) GenerateFS(ctx context.Context, fsys fs.FS, name string, w io.Writer) error {
+	templateFile, err := fsys.Open(name)
+	if err != nil {
+		g.logger.Error("Failed to open template file", slog.String("name", name), slog.String("error", err.Error()))
+		return fmt.Errorf("open template file %q: %w", name, err)
+	}
+	defer templateFile.Close()
+
+	return g.Generate(ctx, templateFile, w)
+}
+
+// Generate orchestrates the report generation:
+// 1. Reads the template workbook from src into memory.
+// 2. Processes each sheet, looking for SQL references in rows.
+// 3. Fetches data and replaces placeholders, expanding table references into one row per record.
+// 4. Writes the finished workbook to w.
+// Nothing is written to disk; SQL files are read from QueriesDir or the file system set with SetQueries. Nothing is
+// written to w either if generation fails. Respects context for cancellation/timeouts. With OnErrorContinue, failed
+// rows don't abort generation; they are listed on ErrorsSheetName and the workbook is written along with an
+// ErrReportIncomplete error.
+func (g *Generator) Generate(ctx context.Context, src io.Reader, w io.Writer) error {
+	assert.Assert(src != nil, "template reader must not be nil")
+	assert.Assert(w != nil, "writer must not be nil")
+
+	// 1. Read the template
+	f, err := g.openWorkbook(src)
+	if err != nil {
+		return err
+	}
+	defer g.closeWorkbook(f)
+
+	// 2. & 3. Process Sheets and Rows
+	if err := g.fill(ctx, f); err != nil {
+		return err
+	}
+
+	// 4. Write the final report
+	g.logger.Debug("Writing generated workbook...")
+	if err := workbookWriter(f)(w); err != nil {
+		g.logger.Error("Failed to write the generated workbook", slog.String("error", err.Error()))
+		return fmt.Errorf("write generated workbook: %w", err)
+	}
+
+	return g.incompleteError()
+}
+
+// FailedOutputPath returns the path the partial workbook of a failed run is kept at with KeepFailed: the output path
+// with ".failed" inserted before its extension, e.g. "report.failed.xlsx".
+func FailedOutputPath(outputPath string) string {
+	ext := filepath.Ext(outputPath)
+	return strings.TrimSuffix(outputPath, ext) + ".failed" + ext
+}
+
+// openWorkbook reads the template workbook from src into memory.
+func (g *Generator) openWorkbook(src io.Reader) (*excelize.File, error) {
+	g.logger.Debug("Reading template workbook")
+	f, err := excelize.OpenReader(src)
+	if err != nil {
+		g.logger.Error("Failed to read template workbook", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("read template workbook: %w", err)
+	}
+	return f, nil
+}
+
+func (g *Generator) closeWorkbook(f *excelize.File) {
+	if err := f.Close(); err != nil {
+		g.logger.Warn("Error closing report workbook", slog.String("error", err.Error()))
+	}
+}
+
+// fill processes all sheets of the workbook, lists failed rows on ErrorsSheetName and updates formulas. The workbook
+// is left partially processed if it returns an error.
+func (g *Generator) fill(ctx context.Context, f *excelize.File) error {
+	if err := g.processSheets(ctx, f); err != nil {
+		return err
+	}
+	if len(g.failures) > 0 {
+		if err := g.writeErrorsSheet(f); err != nil {
+			g.logger.Error("Failed to write errors sheet", slog.String("error", err.Error()))
+			return err
+		}
+	}
+
+	// Update formulas/links before writing, crucial if formulas depend on generated data.
+	g.logger.Debug("Updating linked values and formulas in the workbook...")
+	if err := f.UpdateLinkedValue(); err != nil {
+		g.logger.Warn(
+			"Failed to update linked values/formulas; results may be inconsistent",
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return nil
+}
+
+// keepFailed saves the partially generated workbook of a failed run at FailedOutputPath for debugging. Failing to do
+// so is only logged, so that the error of the run itself is reported.
+func (g *Generator) keepFailed(f *excelize.File, perm os.FileMode) {
+	path := FailedOutputPath(g.config.OutputPath)
+	if err := writeFileAtomic(path, perm, workbookWriter(f)); err != nil {
+		g.logger.Warn("Failed to keep the partial report", slog.String("path", path), slog.String("error", err.Error()))
+		return
+	}
+	g.logger.Warn("Kept the partial report of the failed run", slog.String("path", path))
+}
+
+// DryRun resolves the report like GenerateReport, running every query and evaluating every placeholder, but works on
+// the template in memory and never writes the output file. It returns a plan of what generation would do; with
+// OnErrorContinue the plan is returned along with an ErrReportIncomplete error if rows failed.
+func (g *Generator) DryRun(ctx context.Context) (Plan, error) {
+	g.logger.Info(
+		"Starting dry run",
+		slog.String("template", g.config.TemplatePath),
+		slog.String("output", g.config.OutputPath),
+	)
+
+	g.plan = &Plan{TemplatePath: g.config.TemplatePath, OutputPath: g.config.OutputPath}
+	defer func() { g.plan = nil }()
+
+	f, err := excelize.OpenFile(g.config.TemplatePath)
 	if err != nil {
 		g.logger.Error(
-			"Failed to open copied report file",
-			slog.String("path", g.config.OutputPath),
+			"Failed to open template file",
+			slog.String("path", g.config.TemplatePath),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("open copied report file %q: %w", g.config.OutputPath, err)
+		return Plan{}, fmt.Errorf("open template file %q: %w", g.config.TemplatePath, err)
 	}
 	defer func() {
-		g.logger.Debug("Attempting to close report file", slog.String("path", g.config.OutputPath))
 		if closeErr := f.Close(); closeErr != nil {
-			g.logger.Warn(
-				"Error closing report file",
-				slog.String("path", g.config.OutputPath),
-				slog.String("error", closeErr.Error()),
-			)
+			g.logger.Warn("Error closing template file", slog.String("error", closeErr.Error()))
 		}
 	}()
 
+	if err := g.processSheets(ctx, f); err != nil {
+		return Plan{}, err
+	}
+
+	g.logger.Info("Dry run finished, no output written", slog.Int("row_count", len(g.plan.Rows)))
+	return *g.plan, g.incompleteError()
+}
+
+// processSheets processes all sheets of the opened report file, filling in the data of every referenced query. With a
+// Concurrency above one, the queries run on a pool of workers ahead of the rows, while the rows are still filled in one
+// after another.
+func (g *Generator) processSheets(ctx context.Context, f *excelize.File) error {
+	g.failures = nil
+	g.queryFiles = make(map[string][]byte)
+	g.queries = make(map[string]*pendingQuery)
+
 	// 3. Prepare for Processing
 	sheetList := f.GetSheetList()
 	if len(sheetList) == 0 {
-		err = fmt.Errorf("template file %q contains no sheets", g.config.TemplatePath)
+		err := errors.New("template contains no sheets")
 		g.logger.Error(err.Error())
 		return err
 	}
@@ -124,6 +304,46 @@ func (g *Generator) GenerateReport(ctx context.Context) error {
 		slog.Int("0_based_index", zeroBasedSQLColIndex),
 	)
 
+	// Timestamps with a time zone are returned in the report's time zone.
+	location, err := g.config.Location()
+	if err != nil {
+		g.logger.Error("Invalid report time zone", slog.String("error", err.Error()))
+		return err
+	}
+	ctx = datasource.WithTimezone(ctx, location)
+	if g.config.Decimals == DecimalsExact {
+		ctx = datasource.WithExactDecimals(ctx)
+	}
+
+	// Make sure every query is allowed and its parameters are defined before the first query runs.
+	if err := g.checkQueries(f, sheetList, zeroBasedSQLColIndex); err != nil {
+		g.logger.Error("Query check failed", slog.String("error", err.Error()))
+		return err
+	}
+
+	if g.config.Snapshot {
+		release, err := g.useSnapshot(ctx)
+		if err != nil {
+			g.logger.Error("Failed to take snapshot", slog.String("error", err.Error()))
+			return err
+		}
+		defer release() // Deferred before stopping concurrent queries, so that it runs after them.
+	}
+
+	if g.config.Concurrency > 1 {
+		g.logger.Info("Starting queries concurrently", slog.Int("concurrency", g.config.Concurrency))
+		pending, stop, err := g.startQueries(ctx, f, sheetList, zeroBasedSQLColIndex)
+		if err != nil {
+			g.logger.Error("Failed to start queries", slog.String("error", err.Error()))
+			return err
+		}
+		g.pending = pending
+		defer func() {
+			stop()
+			g.pending = nil
+		}()
+	}
+
 	// 4. Process Sheets and Rows
 	g.logger.Info("Starting sheet processing...")
 	for i, sheetName := range sheetList {
@@ -131,7 +351,7 @@ func (g *Generator) GenerateReport(ctx context.Context) error {
 		sheetLogger.Info("Processing sheet")
 
 		// Process the current sheet, checking context periodically.
-		if err := g.processSheet(ctx, f, sheetName, zeroBasedSQLColIndex, g.config.QueriesDir, sheetLogger); err != nil {
+		if err := g.processSheet(ctx, f, sheetName, zeroBasedSQLColIndex, sheetLogger); err != nil {
 			return fmt.Errorf("processing sheet %q: %w", sheetName, err)
 		}
 
@@ -145,27 +365,26 @@ func (g *Generator) GenerateReport(ctx context.Context) error {
 	}
 	g.logger.Info("Finished processing all sheets.")
 
-	// 5. Save the final report
-	// Update formulas/links before saving, crucial if formulas depend on generated data.
-	g.logger.Debug("Updating linked values and formulas in the workbook...")
-	if err := f.UpdateLinkedValue(); err != nil {
-		g.logger.Warn(
-			"Failed to update linked values/formulas; results may be inconsistent",
-			slog.String("error", err.Error()),
-		)
-	}
+	return nil
+}
 
-	g.logger.Info("Saving generated report...", slog.String("path", g.config.OutputPath))
-	if err := f.Save(); err != nil {
-		g.logger.Error(
-			"Failed to save the generated report file",
-			slog.String("path", g.config.OutputPath),
-			slog.String("error", err.Error()),
-		)
-		return fmt.Errorf("save generated report file %q: %w", g.config.OutputPath, err)
+// useSnapshot routes the queries of the current run to a snapshot of every data source and returns a function that
+// releases the snapshots and restores the data sources.
+func (g *Generator) useSnapshot(ctx context.Context) (func(), error) {
+	snapshot, err := g.sources.Snapshot(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	sources := g.sources
+	g.sources = snapshot
+	return func() {
+		g.sources = sources
+		// Released even if the run was canceled, so that the transactions don't outlive it.
+		if err := snapshot.Close(context.WithoutCancel(ctx)); err != nil {
+			g.logger.Warn("Failed to release snapshot", slog.String("error", err.Error()))
+		}
+	}, nil
 }
 
 // processSheet iterates through rows of a single sheet and triggers row processing.
@@ -175,7 +394,6 @@ func (g *Generator) processSheet(
 	file *excelize.File,
 	sheetName string,
 	zeroBasedSQLColIndex int,
-	queryBaseDir string,
 	logger *slog.Logger,
 ) error {
 	rows, err := file.GetRows(sheetName)
@@ -190,9 +408,11 @@ func (g *Generator) processSheet(
 		return nil
 	}
 
-	// Process each row
+	// Process each row. Table references insert (or remove) rows, so the offset tracks how far the remaining template
+	// rows have shifted relative to their original position.
+	rowOffset := 0
 	for rowIndex, rowCells := range rows {
-		excelRowIndex := rowIndex + 1 // Excel rows are 1-based
+		excelRowIndex := rowIndex + 1 + rowOffset // Excel rows are 1-based
 		rowLogger := logger.With(slog.Int("row_index_excel", excelRowIndex))
 
 		if err := ctx.Err(); err != nil {
@@ -201,14 +421,32 @@ func (g *Generator) processSheet(
 			return fmt.Errorf("%s: %w", errMsg, err) // Return context error
 		}
 
-		if err := g.processRow(ctx, file, sheetName, excelRowIndex, rowCells, zeroBasedSQLColIndex, queryBaseDir, rowLogger); err != nil {
-			return fmt.Errorf("processing row %d: %w", excelRowIndex, err)
+		insertedRows, err := g.processRow(
+			ctx,
+			file,
+			sheetName,
+			excelRowIndex,
+			rowCells,
+			zeroBasedSQLColIndex,
+			g.pending[pendingKey{sheet: sheetName, row: rowIndex + 1}],
+			rowLogger,
+		)
+		rowOffset += insertedRows // Also on failure: a table may have been expanded part way.
+		if err != nil {
+			// Cancellation and timeouts abort generation in either mode.
+			if g.config.OnError != OnErrorContinue || ctx.Err() != nil {
+				return fmt.Errorf("processing row %d: %w", excelRowIndex, err)
+			}
+			g.recordFailure(file, sheetName, excelRowIndex, rowCells, zeroBasedSQLColIndex, err, rowLogger)
 		}
 	}
 	return nil
 }
 
-// processRow handles the logic for a single row: finds SQL ref, fetches data, replaces placeholders.
+// processRow handles the logic for a single row: finds SQL ref, fetches data, replaces placeholders. It returns the
+// number of rows inserted below the row (negative if the row was removed), which is non-zero only for table references.
+// If the row's query was started ahead of it, pending holds it and its result is used instead of running the query
+// again.
 func (g *Generator) processRow(
 	ctx context.Context,
 	file *excelize.File,
@@ -216,29 +454,28 @@ func (g *Generator) processRow(
 	excelRowIndex int,
 	rowCells []string,
 	zeroBasedSQLColIndex int,
-	queryBaseDir string,
+	pending *pendingQuery,
 	logger *slog.Logger,
-) error {
+) (int, error) {
 	// --- 1. Check for SQL Reference ---
 	if len(rowCells) <= zeroBasedSQLColIndex {
-		return nil // Row too short for ref column
+		return 0, nil // Row too short for ref column
 	}
-	sqlFilePathRelative := strings.TrimSpace(rowCells[zeroBasedSQLColIndex])
-	if sqlFilePathRelative == "" {
-		return nil // No SQL reference in this row
+	ref := parseReference(rowCells[zeroBasedSQLColIndex])
+	if ref.Path == "" {
+		return 0, nil // No SQL reference in this row
 	}
-
-	// Construct and clean the absolute path to the SQL file.
-	sqlFilePathAbsolute := filepath.Join(queryBaseDir, sqlFilePathRelative)
-	sqlFilePathAbsolute = filepath.Clean(sqlFilePathAbsolute) // Basic path sanitization
+	sqlFilePathRelative := ref.Path
 
 	logger = logger.With(
 		slog.String("sql_file_relative", sqlFilePathRelative),
-		slog.String("sql_file_absolute", sqlFilePathAbsolute),
+		slog.String("reference_mode", ref.Mode.String()),
+		slog.String("data_source", ref.Source),
 	)
 	logger.Info("Found SQL reference, processing row")
 
 	// --- 2. Clear the SQL Reference Cell ---
+	// Cleared before a table prototype is duplicated, so copies of the row don't carry the reference either.
 	sqlCellAxis, err := excelize.CoordinatesToCellName(zeroBasedSQLColIndex+1, excelRowIndex)
 	if err != nil {
 		logger.Error(
@@ -254,47 +491,169 @@ func (g *Generator) processRow(
 
 	// --- 3. Read SQL Query File ---
 	logger.Debug("Reading SQL query file")
-	queryBytes, err := os.ReadFile(sqlFilePathAbsolute)
+	queryBytes, err := g.readQueryFile(sqlFilePathRelative)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			logger.Error("Referenced SQL file not found", slog.String("error", err.Error()))
-			return fmt.Errorf("referenced SQL file not found at %q", sqlFilePathAbsolute)
+			return 0, fmt.Errorf("referenced SQL file not found: %q", sqlFilePathRelative)
 		}
 		logger.Error("Failed to read SQL file", slog.String("error", err.Error()))
-		return fmt.Errorf("read SQL file %q: %w", sqlFilePathAbsolute, err)
+		return 0, fmt.Errorf("read SQL file %q: %w", sqlFilePathRelative, err)
 	}
 
+	planRow := g.planRow(sheetName, excelRowIndex, ref)
+
 	query := string(queryBytes)
 	trimmedQuery := strings.TrimSpace(query)
 	if trimmedQuery == "" {
 		logger.Warn("Skipping data fetch and replacement: SQL file is empty or contains only whitespace.")
-		return nil
+		planRow.skip("SQL file is empty")
+		return 0, nil
 	}
+	planRow.setQuery(trimmedQuery, g.config.Params)
 	logger.Debug("SQL query read successfully", slog.String("query", trimmedQuery))
 
+	source, err := g.sources.Lookup(ref.Source)
+	if err != nil {
+		logger.Error("Failed to resolve data source for reference", slog.String("error", err.Error()))
+		return 0, fmt.Errorf("resolve data source for %q: %w", sqlFilePathRelative, err)
+	}
+
+	// Queries that weren't started ahead of the row run now, unless an identical query ran before.
+	if pending == nil {
+		pending = g.runQuery(ctx, ref.Source, source, ref.Mode == referenceModeTable, trimmedQuery)
+	}
+
+	if ref.Mode == referenceModeTable {
+		return g.processTableRow(
+			ctx,
+			file,
+			sheetName,
+			excelRowIndex,
+			rowCells,
+			zeroBasedSQLColIndex,
+			sqlFilePathRelative,
+			pending,
+			planRow,
+			logger,
+		)
+	}
+
 	// --- 4. Fetch Data ---
 	logger.Debug("Fetching data from data source")
-	dataMap, err := g.dataSource.FetchData(ctx, trimmedQuery)
+	dataMap, err := fetchData(ctx, pending)
 	if err != nil {
 		if errors.Is(err, datasource.ErrQueryReturnedNoRows) {
 			logger.Warn("SQL query returned no rows, skipping replacements for this row.")
-			return nil
+			planRow.skip("query returned no rows")
+			return 0, nil
+		}
+		if errors.Is(err, datasource.ErrQueryReturnedMultipleRows) {
+			logger.Error(
+				"SQL query returned multiple rows; prefix the reference with "+tableModeTag+" to expand it as a table",
+				slog.String("error", err.Error()),
+			)
+			return 0, fmt.Errorf("fetch data using query from %q: %w", sqlFilePathRelative, err)
 		}
 
 		logger.Error(
 			"Failed to fetch data from data source, skipping row processing.",
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("fetch data using query from %q: %w", sqlFilePathAbsolute, err)
+		return 0, fmt.Errorf("fetch data using query from %q: %w", sqlFilePathRelative, err)
 	}
 
 	if len(dataMap) == 0 {
 		logger.Warn("Skipping marker replacement: Fetched data map is empty.")
-		return nil
+		planRow.skip("query returned no columns")
+		return 0, nil
 	}
 	logger.Debug("Data fetched successfully", slog.Any("data_keys", getMapKeys(dataMap)))
+	planRow.setRecords(1)
 
 	// --- 5. Replace Placeholders in Cells ---
+	g.fillRow(file, sheetName, excelRowIndex, rowCells, zeroBasedSQLColIndex, dataMap, planRow, logger)
+
+	logger.Info("Finished processing row")
+	return 0, nil
+}
+
+// processTableRow expands a table reference: the row acts as a prototype that is duplicated, including its styles and
+// placeholders, once for every record returned by the query. Rows below the prototype are shifted down accordingly, and
+// formula ranges ending at the prototype row, like the SUM of a totals row, are extended over the inserted rows. A
+// query without records removes the prototype row. Returns the number of rows inserted (or -1 if removed), also if
+// inserting the rows failed part way.
+func (g *Generator) processTableRow(
+	ctx context.Context,
+	file *excelize.File,
+	sheetName string,
+	excelRowIndex int,
+	rowCells []string,
+	zeroBasedSQLColIndex int,
+	sqlFilePathRelative string,
+	pending *pendingQuery,
+	planRow *PlanRow,
+	logger *slog.Logger,
+) (int, error) {
+	logger.Debug("Fetching table rows from data source")
+	records, err := fetchRows(ctx, pending)
+	if err != nil {
+		logger.Error(
+			"Failed to fetch table rows from data source, skipping row processing.",
+			slog.String("error", err.Error()),
+		)
+		return 0, fmt.Errorf("fetch rows using query from %q: %w", sqlFilePathRelative, err)
+	}
+
+	if len(records) == 0 {
+		logger.Warn("Table query returned no rows, removing prototype row.")
+		planRow.skip("query returned no rows, the row is removed")
+		if err := file.RemoveRow(sheetName, excelRowIndex); err != nil {
+			logger.Error("Failed to remove prototype row", slog.String("error", err.Error()))
+			return 0, fmt.Errorf("remove prototype row %d: %w", excelRowIndex, err)
+		}
+		return -1, nil
+	}
+	logger.Debug("Table rows fetched successfully", slog.Int("record_count", len(records)))
+	planRow.setRecords(len(records))
+
+	// Every duplicate is inserted directly below the prototype, which still holds the raw placeholders at this point.
+	for inserted := range len(records) - 1 {
+		if err := file.DuplicateRow(sheetName, excelRowIndex); err != nil {
+			logger.Error("Failed to duplicate prototype row", slog.String("error", err.Error()))
+			return inserted, fmt.Errorf("duplicate prototype row %d: %w", excelRowIndex, err)
+		}
+	}
+	if err := extendFormulaRanges(file, sheetName, excelRowIndex, len(records)-1); err != nil {
+		logger.Error("Failed to extend formula ranges over the table", slog.String("error", err.Error()))
+		return len(records) - 1, fmt.Errorf("extend formula ranges over table rows: %w", err)
+	}
+
+	for i, record := range records {
+		targetRowIndex := excelRowIndex + i
+		recordLogger := logger.With(slog.Int("table_row_index_excel", targetRowIndex))
+		g.fillRow(file, sheetName, targetRowIndex, rowCells, zeroBasedSQLColIndex, record, planRow, recordLogger)
+	}
+
+	logger.Info("Finished processing table row", slog.Int("record_count", len(records)))
+	return len(records) - 1, nil
+}
+
+// fillRow replaces the placeholders of the given template cells with values from the data map and the report
+// parameters, and writes the results into the given row. Failures of individual cells are logged and leave the original
+// cell value in place. Every resolved cell is recorded in planRow, which is nil unless this is a dry run.
+func (g *Generator) fillRow(
+	file *excelize.File,
+	sheetName string,
+	excelRowIndex int,
+	rowCells []string,
+	zeroBasedSQLColIndex int,
+	dataMap map[string]any,
+	planRow *PlanRow,
+	logger *slog.Logger,
+) {
+	templateData := g.templateData(dataMap, logger)
+
 	logger.Debug("Scanning row cells for placeholders...")
 	for cellIndex, originalCellValue := range rowCells {
 		// Skip the SQL ref column itself and cells without template markers.
@@ -307,26 +666,39 @@ func (g *Generator) processRow(
 		cellLogger := logger.With(slog.String("cell", cellAxis), slog.String("template_content", originalCellValue))
 		cellLogger.Debug("Found potential template, processing cell content")
 
-		// Process the cell content using the fetched data.
-		processedValue, err := processTemplate(originalCellValue, dataMap)
+		// Process the cell content using the fetched data. Cells formatted as text keep the output as written.
+		processedValue, err := processTemplate(originalCellValue, templateData, !isTextCell(file, sheetName, cellAxis))
 		if err != nil {
 			cellLogger.Warn(
 				"Failed to process cell content template (leaving original value)",
 				slog.String("error", err.Error()),
 			)
+			planRow.addCell(PlanCell{Cell: cellAxis, Template: originalCellValue, Error: err.Error()})
 			continue
 		}
 
 		// Encode maps/slices/pointers to JSON strings for Excel compatibility.
-		finalValue, err := encodeComplexTypes(processedValue)
+		finalValue, err := encodeComplexTypes(processedValue, g.config.CollectionFormat)
 		if err != nil {
 			cellLogger.Error(
 				"Failed to encode complex data type for cell",
 				slog.Any("value", processedValue),
 				slog.String("error", err.Error()),
 			)
+			planRow.addCell(PlanCell{Cell: cellAxis, Template: originalCellValue, Error: err.Error()})
 			continue // Continue processing other cells
 		}
+		planRow.addCell(PlanCell{Cell: cellAxis, Template: originalCellValue, Value: finalValue})
+
+		if d, ok := finalValue.(decimal.Decimal); ok {
+			finalValue = decimalCellValue(d, cellLogger)
+		}
+		if t, ok := finalValue.(time.Time); ok {
+			if err := g.applyDefaultDateFormat(file, sheetName, cellAxis, t); err != nil {
+				cellLogger.Warn("Failed to apply default date format", slog.String("error", err.Error()))
+			}
+			finalValue = excelTime(t)
+		}
 
 		// Optimization: Only update cell if the value actually changed.
 		if fmt.Sprint(finalValue) != originalCellValue {
@@ -342,15 +714,102 @@ func (g *Generator) processRow(
 			cellLogger.Debug("Skipping cell update: Processed value is same as original.")
 		}
 	}
+}
+
+// planRow starts recording a referenced row if this is a dry run and returns nil otherwise. The row is recorded even if
+// it is skipped later on.
+func (g *Generator) planRow(sheetName string, excelRowIndex int, ref reference) *PlanRow {
+	if g.plan == nil {
+		return nil
+	}
+	g.plan.Rows = append(g.plan.Rows, PlanRow{
+		Sheet:      sheetName,
+		Row:        excelRowIndex,
+		SQLFile:    ref.Path,
+		DataSource: ref.Source,
+		Mode:       ref.Mode.String(),
+	})
+	return &g.plan.Rows[len(g.plan.Rows)-1]
+}
+
+// templateData returns the data cell templates are evaluated against: the query's columns plus the report parameters
+// under ParamsTemplateKey, which takes precedence over a column of the same name.
+func (g *Generator) templateData(dataMap map[string]any, logger *slog.Logger) map[string]any {
+	if _, exists := dataMap[ParamsTemplateKey]; exists {
+		logger.Warn(
+			"Query column is shadowed by the report parameters in templates; rename the column to use it",
+			slog.String("column", ParamsTemplateKey),
+		)
+	}
+
+	data := make(map[string]any, len(dataMap)+1)
+	for key, value := range dataMap {
+		if infinite, ok := value.(datasource.InfiniteTime); ok {
+			data[key] = g.infinityValue(infinite)
+			continue
+		}
+		data[key] = value
+	}
+
+	params := g.config.Params
+	if params == nil {
+		params = map[string]any{}
+	}
+	data[ParamsTemplateKey] = params
+
+	return data
+}
+
+// checkQueries scans the reference column of every sheet and verifies that each referenced query is a single statement
+// of an allowed type and only uses parameters that are defined in the report configuration. Unreadable and empty SQL
+// files are skipped here; they are reported when the row is processed.
+func (g *Generator) checkQueries(file *excelize.File, sheetList []string, zeroBasedSQLColIndex int) error {
+	var problems []string
+	checked := make(map[string]bool)
+
+	for _, sheetName := range sheetList {
+		rows, err := file.GetRows(sheetName)
+		if err != nil {
+			return fmt.Errorf("get rows from sheet %q: %w", sheetName, err)
+		}
+
+		for _, rowCells := range rows {
+			if len(rowCells) <= zeroBasedSQLColIndex {
+				continue
+			}
+			ref := parseReference(rowCells[zeroBasedSQLColIndex])
+			if ref.Path == "" || checked[ref.Path] {
+				continue
+			}
+			checked[ref.Path] = true
+
+			queryBytes, err := g.readQueryFile(ref.Path)
+			if err != nil || strings.TrimSpace(string(queryBytes)) == "" {
+				continue
+			}
+
+			if err := datasource.CheckStatement(string(queryBytes), g.config.AllowedStatements); err != nil {
+				return fmt.Errorf("SQL file %q: %w", ref.Path, err)
+			}
+			for _, name := range datasource.QueryParamNames(string(queryBytes)) {
+				if _, ok := g.config.Params[name]; !ok {
+					problems = append(problems, fmt.Sprintf("%q in %q", name, ref.Path))
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", datasource.ErrUndefinedParameter, strings.Join(problems, ", "))
+	}
 
-	logger.Info("Finished processing row")
 	return nil
 }
 
-// encodeComplexTypes checks if a value is a map, slice, or pointer to one,
-// and marshals it to a JSON string if so. Otherwise, returns the value unchanged.
+// encodeComplexTypes checks if a value is a map, slice, or pointer to one, and renders it as a string in the given
+// collection format if so: as JSON, or as a comma or newline separated list. Otherwise, returns the value unchanged.
 // This helps embed complex data structures into Excel cells legibly.
-func encodeComplexTypes(v any) (any, error) {
+func encodeComplexTypes(v any, format string) (any, error) {
 	if v == nil {
 		return nil, nil
 	}
@@ -371,6 +830,13 @@ func encodeComplexTypes(v any) (any, error) {
 			return string(rv.Bytes()), nil
 		}
 
+		switch format {
+		case CollectionFormatComma:
+			return joinCollection(rv, ", ")
+		case CollectionFormatNewline:
+			return joinCollection(rv, "\n")
+		}
+
 		jsonData, err := json.Marshal(v)
 		if err != nil {
 			return nil, fmt.Errorf("marshal complex type (%T) to JSON: %w", v, err)
@@ -382,19 +848,21 @@ func encodeComplexTypes(v any) (any, error) {
 	}
 }
 
-// Simple template placeholder regex: {{ .key }} or {{.key}} (with optional whitespace).
-var simpleTemplateRegex = regexp.MustCompile(`^\s*\{\{\s*\.\s*([a-zA-Z0-9_]+)\s*\}\}\s*$`)
+// Simple template placeholder regex: {{ .key }} or {{.key}} (with optional whitespace), optionally addressing nested
+// object fields like {{ .key.field }}.
+var simpleTemplateRegex = regexp.MustCompile(`^\s*\{\{\s*\.\s*([a-zA-Z0-9_]+(?:\.[a-zA-Z0-9_]+)*)\s*\}\}\s*$`)
 
 const simpleTemplateRegexKeyIndex = 1 // Index of the capture group for the key name.
 
 // processTemplate evaluates a cell's content using the provided data map. It uses a fast path for simple `{{ .key }}`
-// placeholders and falls back to the full `text/template` engine for more complex expressions.
-func processTemplate(cellContent string, dataMap map[string]any) (any, error) {
+// and `{{ .key.field }}` placeholders, which keeps the value's type, and falls back to the full `text/template` engine
+// for more complex expressions. If coerce is set, the string output of the template engine is converted back into a
+// number, boolean or date where possible, unless the template formats its output with one of the formattingFuncs.
+func processTemplate(cellContent string, dataMap map[string]any, coerce bool) (any, error) {
 	// Fast path: Check if the entire cell content matches the simple `{{ .key }}` pattern.
 	matches := simpleTemplateRegex.FindStringSubmatch(cellContent)
 	if len(matches) == simpleTemplateRegexKeyIndex+1 {
-		key := matches[simpleTemplateRegexKeyIndex]
-		if value, ok := dataMap[key]; ok {
+		if value, ok := lookupPath(dataMap, strings.Split(matches[simpleTemplateRegexKeyIndex], ".")); ok {
 			return value, nil // Key found
 		}
 
@@ -403,11 +871,9 @@ func processTemplate(cellContent string, dataMap map[string]any) (any, error) {
 
 	// Fallback: Use text/template for complex templates or if simple match failed/key missing.
 	// Note: text/template always produces a string output.
-	tmpl, err := template.New("cell").
-		Option("missingkey=error"). // Missing key will return an error instead of ignoring it.
-		Parse(cellContent)
+	tmpl, err := parseCellTemplate(cellContent)
 	if err != nil {
-		return nil, fmt.Errorf("parse cell template: %w", err)
+		return nil, err
 	}
 
 	var buf bytes.Buffer
@@ -415,44 +881,123 @@ func processTemplate(cellContent string, dataMap map[string]any) (any, error) {
 		return nil, fmt.Errorf("execute cell template: %w", err)
 	}
 
+	if coerce && !callsFormattingFunc(tmpl) {
+		return coerceTemplateOutput(buf.String()), nil
+	}
 	return buf.String(), nil
 }
 
-func copyFile(src, dst string) error {
-	sourceFileStat, err := os.Stat(src)
+// nullToEmptyFunc is the name under which printNullAsEmpty adds nullToEmpty to cell templates.
+const nullToEmptyFunc = "excaliburNullToEmpty"
+
+// parseCellTemplate parses a cell's content as a text/template, configured the same way for generation and validation.
+func parseCellTemplate(cellContent string) (*template.Template, error) {
+	tmpl, err := template.New("cell").
+		Option("missingkey=error"). // Missing key will return an error instead of ignoring it.
+		Funcs(templateFuncs()).
+		Funcs(template.FuncMap{nullToEmptyFunc: nullToEmpty}).
+		Parse(cellContent)
 	if err != nil {
-		return fmt.Errorf("stat source file %q: %w", src, err)
+		return nil, fmt.Errorf("parse cell template: %w", err)
 	}
-	if !sourceFileStat.Mode().IsRegular() {
-		return fmt.Errorf("source %q is not a regular file", src)
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			printNullAsEmpty(t.Tree, t.Tree.Root)
+		}
 	}
+	return tmpl, nil
+}
 
-	source, err := os.Open(src)
-	if err != nil {
-		return fmt.Errorf("open source file %q: %w", src, err)
+// printNullAsEmpty pipes the result of every action below node that prints a value through nullToEmpty. Otherwise
+// text/template prints NULL, e.g. from `{{ .discount | round 2 }}` or `{{ add .net .tax }}`, as "<no value>".
+func printNullAsEmpty(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			printNullAsEmpty(tree, child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			return // Assignments like `{{ $total := .sum }}` print nothing.
+		}
+		identifier := parse.NewIdentifier(nullToEmptyFunc).SetTree(tree).SetPos(n.Pipe.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pipe.Pos,
+			Args:     []parse.Node{identifier},
+		})
+	case *parse.IfNode:
+		printNullAsEmpty(tree, n.List)
+		printNullAsEmpty(tree, n.ElseList)
+	case *parse.RangeNode:
+		printNullAsEmpty(tree, n.List)
+		printNullAsEmpty(tree, n.ElseList)
+	case *parse.WithNode:
+		printNullAsEmpty(tree, n.List)
+		printNullAsEmpty(tree, n.ElseList)
+	}
+}
+
+// nullToEmpty returns an empty string for NULL (nil) and any other value unchanged.
+func nullToEmpty(value any) any {
+	if value == nil {
+		return ""
 	}
-	defer source.Close()
+	return value
+}
 
-	dstDir := filepath.Dir(dst)
-	if err = os.MkdirAll(dstDir, 0o750); err != nil {
-		return fmt.Errorf("create destination directory %q: %w", dstDir, err)
+// workbookWriter returns a function writing the workbook to a writer, for writeFileAtomic.
+func workbookWriter(f *excelize.File) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := f.WriteTo(w)
+		return err
 	}
+}
 
-	// Create dest file, truncating if exists, using source permissions.
-	destination, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, sourceFileStat.Mode())
-	if err != nil {
-		return fmt.Errorf("create destination file %q: %w", dst, err)
+// writeFileAtomic writes a file through write, creating its directory if needed. The contents are written to a
+// temporary file in the same directory, synced and renamed over path, so that path is either left as it was or
+// replaced as a whole, even if the process dies midway.
+func writeFileAtomic(path string, perm os.FileMode, write func(io.Writer) error) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("create directory %q: %w", dir, err)
 	}
-	defer destination.Close()
 
-	_, err = io.Copy(destination, source)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
 	if err != nil {
-		return fmt.Errorf("copy content from %q to %q: %w", src, dst, err)
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	renamed := false
+	defer func() {
+		if !renamed {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := write(tmp); err != nil {
+		return errors.Join(fmt.Errorf("write temporary file: %w", err), tmp.Close())
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return errors.Join(fmt.Errorf("set permissions of temporary file: %w", err), tmp.Close())
+	}
+	if err := tmp.Sync(); err != nil {
+		return errors.Join(fmt.Errorf("sync temporary file: %w", err), tmp.Close())
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temporary file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename temporary file: %w", err)
 	}
+	renamed = true
 
-	// Ensure the destination file is synced to disk.
-	if err = destination.Sync(); err != nil {
-		return fmt.Errorf("sync destination file %q: %w", dst, err)
+	// Persist the rename itself. Directories can't be synced on every platform, so this is best effort.
+	if d, err := os.Open(dir); err == nil { //nolint:gosec // The directory of the output path.
+		_ = d.Sync()
+		_ = d.Close()
 	}
 
 	return nil
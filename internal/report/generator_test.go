@@ -0,0 +1,978 @@
+package report_test
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+const testSheet = "Sheet1"
+
+// fakeDataSource serves canned results keyed by the trimmed query text and records the parameters of every query.
+type fakeDataSource struct {
+	rows map[string][]map[string]any
+
+	mu     sync.Mutex
+	params []map[string]any
+}
+
+func (f *fakeDataSource) FetchData(_ context.Context, query string, params map[string]any) (map[string]any, error) {
+	f.recordParams(params)
+	records := f.rows[strings.TrimSpace(query)]
+	switch len(records) {
+	case 0:
+		return nil, datasource.ErrQueryReturnedNoRows
+	case 1:
+		return records[0], nil
+	default:
+		return nil, datasource.ErrQueryReturnedMultipleRows
+	}
+}
+
+func (f *fakeDataSource) FetchRows(_ context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	f.recordParams(params)
+	return f.rows[strings.TrimSpace(query)], nil
+}
+
+func (f *fakeDataSource) Close(_ context.Context) error { return nil }
+
+func (f *fakeDataSource) recordParams(params map[string]any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.params = append(f.params, params)
+}
+
+// blockingDataSource holds every query until as many queries as its barrier counts are running at the same time, or
+// until the query's context is done.
+type blockingDataSource struct {
+	fakeDataSource
+	barrier sync.WaitGroup
+}
+
+func (b *blockingDataSource) FetchData(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
+	if err := b.arrive(ctx); err != nil {
+		return nil, err
+	}
+	return b.fakeDataSource.FetchData(ctx, query, params)
+}
+
+func (b *blockingDataSource) FetchRows(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	if err := b.arrive(ctx); err != nil {
+		return nil, err
+	}
+	return b.fakeDataSource.FetchRows(ctx, query, params)
+}
+
+func (b *blockingDataSource) arrive(ctx context.Context) error {
+	b.barrier.Done()
+
+	released := make(chan struct{})
+	go func() {
+		b.barrier.Wait()
+		close(released)
+	}()
+
+	select {
+	case <-released:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// testWorkspace creates a template with the given cells, a queries directory with the given SQL files and returns a
+// report config pointing at them.
+func testWorkspace(t *testing.T, cells map[string]any, queries map[string]string) report.Config {
+	t.Helper()
+
+	baseDir := t.TempDir()
+	queriesDir := filepath.Join(baseDir, "queries")
+	require.NoError(t, os.Mkdir(queriesDir, 0o750))
+	for name, query := range queries {
+		require.NoError(t, os.WriteFile(filepath.Join(queriesDir, name), []byte(query), 0o600))
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	for cell, value := range cells {
+		require.NoError(t, f.SetCellValue(testSheet, cell, value))
+	}
+	templatePath := filepath.Join(baseDir, "template.xlsx")
+	require.NoError(t, f.SaveAs(templatePath))
+
+	return report.Config{
+		TemplatePath:        templatePath,
+		DataSourceRefColumn: "D",
+		QueriesDir:          queriesDir,
+		OutputPath:          filepath.Join(baseDir, "output", "report.xlsx"),
+		Timeout:             time.Minute,
+	}
+}
+
+func generate(t *testing.T, cfg report.Config, sources map[string]datasource.DataSource) [][]string {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	generator := report.NewGenerator(datasource.NewRegistry(sources, logger), cfg, logger)
+	require.NoError(t, generator.GenerateReport(t.Context()))
+
+	return readSheet(t, cfg.OutputPath)
+}
+
+// readSheet returns the cell values of the test sheet of a generated report.
+func readSheet(t *testing.T, path string) [][]string {
+	t.Helper()
+
+	f, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows(testSheet)
+	require.NoError(t, err)
+	return rows
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	t.Parallel()
+
+	template := excelize.NewFile()
+	defer template.Close()
+	require.NoError(t, template.SetCellValue(testSheet, "A1", "Total"))
+	require.NoError(t, template.SetCellValue(testSheet, "B1", "{{ .total }}"))
+	require.NoError(t, template.SetCellValue(testSheet, "D1", "sales/total.sql"))
+	templateBytes, err := template.WriteToBuffer()
+	require.NoError(t, err)
+
+	// Neither the template, the queries nor the output touch disk.
+	fsys := fstest.MapFS{
+		"template.xlsx":           {Data: templateBytes.Bytes()},
+		"queries/sales/total.sql": {Data: []byte("SELECT total FROM totals")},
+	}
+	queries, err := fs.Sub(fsys, "queries")
+	require.NoError(t, err)
+	source := &fakeDataSource{rows: map[string][]map[string]any{"SELECT total FROM totals": {{"total": 42}}}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sources := datasource.NewRegistry(map[string]datasource.DataSource{datasource.DefaultSourceName: source}, logger)
+
+	generator := report.NewGenerator(sources, report.Config{DataSourceRefColumn: "D"}, logger)
+	generator.SetQueries(queries)
+
+	var fromReader, fromFS bytes.Buffer
+	require.NoError(t, generator.Generate(t.Context(), bytes.NewReader(templateBytes.Bytes()), &fromReader))
+	require.NoError(t, generator.GenerateFS(t.Context(), fsys, "template.xlsx", &fromFS))
+
+	for _, output := range []*bytes.Buffer{&fromReader, &fromFS} {
+		f, err := excelize.OpenReader(output)
+		require.NoError(t, err)
+		rows, err := f.GetRows(testSheet)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		assert.Equal(t, [][]string{{"Total", "42"}}, rows)
+	}
+}
+
+func TestGenerateReport_FailureKeepsPreviousOutput(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		keepFailed bool
+	}{
+		{name: "Discard Partial Report"},
+		{name: "Keep Partial Report", keepFailed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testWorkspace(t,
+				map[string]any{"A1": "{{ .total }}", "D1": "total.sql", "A2": "{{ .count }}", "D2": "missing.sql"},
+				map[string]string{"total.sql": "SELECT total FROM totals"},
+			)
+			cfg.KeepFailed = tt.keepFailed
+			outputDir := filepath.Dir(cfg.OutputPath)
+			require.NoError(t, os.MkdirAll(outputDir, 0o750))
+			require.NoError(t, os.WriteFile(cfg.OutputPath, []byte("previous report"), 0o600))
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			source := &fakeDataSource{rows: map[string][]map[string]any{"SELECT total FROM totals": {{"total": 42}}}}
+			sources := datasource.NewRegistry(map[string]datasource.DataSource{datasource.DefaultSourceName: source}, logger)
+
+			err := report.NewGenerator(sources, cfg, logger).GenerateReport(t.Context())
+			require.ErrorContains(t, err, "referenced SQL file not found")
+
+			previous, err := os.ReadFile(cfg.OutputPath)
+			require.NoError(t, err)
+			assert.Equal(t, "previous report", string(previous))
+
+			entries, err := os.ReadDir(outputDir)
+			require.NoError(t, err)
+			if !tt.keepFailed {
+				require.Len(t, entries, 1, "temporary file left behind")
+				return
+			}
+
+			require.Len(t, entries, 2, "temporary file left behind")
+			assert.Equal(t, filepath.Join(outputDir, "report.failed.xlsx"), report.FailedOutputPath(cfg.OutputPath))
+			rows := readSheet(t, report.FailedOutputPath(cfg.OutputPath))
+			assert.Equal(t, [][]string{{"42"}, {"{{ .count }}"}}, rows) // Processing stopped at the failed row.
+		})
+	}
+}
+
+func TestGenerateReport_TableExpansion(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{
+			"A1": "Region", "B1": "Sales",
+			"A2": "{{ .region }}", "B2": "{{ .sales }}", "D2": "[table] regions.sql",
+			"A3": "Total", "B3": "{{ .total }}", "D3": "total.sql",
+		},
+		map[string]string{
+			"regions.sql": "SELECT region, sales FROM regions",
+			"total.sql":   "SELECT total FROM totals",
+		},
+	)
+	source := &fakeDataSource{rows: map[string][]map[string]any{
+		"SELECT region, sales FROM regions": {
+			{"region": "NORTH", "sales": 10},
+			{"region": "SOUTH", "sales": 20},
+			{"region": "WEST", "sales": 30},
+		},
+		"SELECT total FROM totals": {{"total": 60}},
+	}}
+
+	// Formulas over the prototype row must cover every record once the table is expanded.
+	template, err := excelize.OpenFile(cfg.TemplatePath)
+	require.NoError(t, err)
+	require.NoError(t, template.SetCellFormula(testSheet, "C1", "COUNTA(A1:A2)"))
+	require.NoError(t, template.SetCellFormula(testSheet, "C3", "SUM(B2:B2)+SUM($B$2:$B$2)"))
+	require.NoError(t, template.SetCellFormula(testSheet, "E3", "B2*2"))
+	require.NoError(t, template.Save())
+	require.NoError(t, template.Close())
+
+	rows := generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: source})
+
+	assert.Equal(t, [][]string{
+		{"Region", "Sales", ""}, // Formulas are calculated by Excel.
+		{"NORTH", "10"},
+		{"SOUTH", "20"},
+		{"WEST", "30"},
+		{"Total", "60", "", "", ""},
+	}, rows)
+
+	f, err := excelize.OpenFile(cfg.OutputPath)
+	require.NoError(t, err)
+	defer f.Close()
+	for cell, expected := range map[string]string{
+		"C1": "COUNTA(A1:A4)",
+		"C5": "SUM(B2:B4)+SUM($B$2:$B$4)",
+		"E5": "B2*2", // Single cells aren't ranges.
+	} {
+		formula, err := f.GetCellFormula(testSheet, cell)
+		require.NoError(t, err)
+		assert.Equal(t, expected, formula, "cell %s", cell)
+	}
+}
+
+func TestGenerateReport_TableExpansionWithoutRecords(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{
+			"A1": "Region",
+			"A2": "{{ .region }}", "D2": "[TABLE] regions.sql",
+			"A3": "End",
+		},
+		map[string]string{"regions.sql": "SELECT region FROM regions"},
+	)
+
+	rows := generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: &fakeDataSource{}})
+
+	assert.Equal(t, [][]string{{"Region"}, {"End"}}, rows)
+}
+
+func TestGenerateReport_NamedDataSources(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{
+			"A1": "{{ .revenue }}", "D1": "warehouse:revenue.sql",
+			"A2": "{{ .open_tickets }}", "D2": "ops: tickets.sql",
+			"A3": "{{ .region }}", "D3": "[table] warehouse:regions.sql",
+		},
+		map[string]string{
+			"revenue.sql": "SELECT revenue",
+			"tickets.sql": "SELECT open_tickets",
+			"regions.sql": "SELECT region",
+		},
+	)
+	sources := map[string]datasource.DataSource{
+		"warehouse": &fakeDataSource{rows: map[string][]map[string]any{
+			"SELECT revenue": {{"revenue": 1000}},
+			"SELECT region":  {{"region": "NORTH"}, {"region": "SOUTH"}},
+		}},
+		"ops": &fakeDataSource{rows: map[string][]map[string]any{
+			"SELECT open_tickets": {{"open_tickets": 7}},
+		}},
+	}
+
+	rows := generate(t, cfg, sources)
+
+	assert.Equal(t, [][]string{{"1000"}, {"7"}, {"NORTH"}, {"SOUTH"}}, rows)
+}
+
+func TestGenerateReport_QueryPathWithLeadingSlash(t *testing.T) {
+	t.Parallel()
+
+	// References are relative to the queries directory, also with a leading slash.
+	cfg := testWorkspace(t,
+		map[string]any{"A1": "{{ .revenue }}", "D1": "/revenue.sql", "A2": "{{ .region }}", "D2": "[table] /regions.sql"},
+		map[string]string{"revenue.sql": "SELECT revenue", "regions.sql": "SELECT region"},
+	)
+	source := &fakeDataSource{rows: map[string][]map[string]any{
+		"SELECT revenue": {{"revenue": 1000}},
+		"SELECT region":  {{"region": "NORTH"}},
+	}}
+
+	rows := generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: source})
+
+	assert.Equal(t, [][]string{{"1000"}, {"NORTH"}}, rows)
+}
+
+func TestGenerateReport_UnknownDataSource(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{"A1": "{{ .revenue }}", "D1": "archive:revenue.sql"},
+		map[string]string{"revenue.sql": "SELECT revenue"},
+	)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sources := datasource.NewRegistry(map[string]datasource.DataSource{
+		"warehouse": &fakeDataSource{},
+		"ops":       &fakeDataSource{},
+	}, logger)
+
+	err := report.NewGenerator(sources, cfg, logger).GenerateReport(t.Context())
+
+	require.ErrorIs(t, err, datasource.ErrUnknownDataSource)
+	assert.ErrorContains(t, err, `"archive"`)
+}
+
+func TestGenerateReport_Params(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{
+			"A1": "{{ .params.region }} sales in {{ .params.month.Format \"2006-01\" }}",
+			"B1": "{{ .sales }}", "D1": "sales.sql",
+		},
+		map[string]string{"sales.sql": "SELECT sales FROM s WHERE region = :region AND month = :month"},
+	)
+	cfg.Params = map[string]any{"region": "NORTH", "month": time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}
+	source := &fakeDataSource{rows: map[string][]map[string]any{
+		"SELECT sales FROM s WHERE region = :region AND month = :month": {{"sales": 42}},
+	}}
+
+	rows := generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: source})
+
+	assert.Equal(t, [][]string{{"NORTH sales in 2026-10", "42"}}, rows)
+	assert.Equal(t, []map[string]any{cfg.Params}, source.params)
+}
+
+func TestGenerateReport_UndefinedParamFailsBeforeAnyQuery(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{
+			"A1": "{{ .total }}", "D1": "total.sql",
+			"A2": "{{ .sales }}", "D2": "sales.sql",
+		},
+		map[string]string{
+			"total.sql": "SELECT total FROM totals",
+			"sales.sql": "SELECT sales FROM s WHERE region = :region",
+		},
+	)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	source := &fakeDataSource{}
+	sources := datasource.NewRegistry(map[string]datasource.DataSource{datasource.DefaultSourceName: source}, logger)
+
+	err := report.NewGenerator(sources, cfg, logger).GenerateReport(t.Context())
+
+	require.ErrorIs(t, err, datasource.ErrUndefinedParameter)
+	assert.ErrorContains(t, err, `"region" in "sales.sql"`)
+	assert.Empty(t, source.params)
+}
+
+func TestGenerateReport_DisallowedStatementFailsBeforeAnyQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr error
+	}{
+		{name: "Disallowed Type", query: "DELETE FROM sales RETURNING id", wantErr: datasource.ErrStatementNotAllowed},
+		{name: "Multiple Statements", query: "SELECT 1; DROP TABLE sales", wantErr: datasource.ErrMultipleStatements},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testWorkspace(t,
+				map[string]any{
+					"A1": "{{ .total }}", "D1": "total.sql",
+					"A2": "{{ .id }}", "D2": "cleanup.sql",
+				},
+				map[string]string{"total.sql": "SELECT total FROM totals", "cleanup.sql": tt.query},
+			)
+			cfg.AllowedStatements = []string{"SELECT", "WITH"}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			source := &fakeDataSource{}
+			sources := datasource.NewRegistry(map[string]datasource.DataSource{datasource.DefaultSourceName: source}, logger)
+
+			err := report.NewGenerator(sources, cfg, logger).GenerateReport(t.Context())
+
+			require.ErrorIs(t, err, tt.wantErr)
+			assert.ErrorContains(t, err, `"cleanup.sql"`)
+			assert.Empty(t, source.params)
+		})
+	}
+}
+
+func TestGenerator_DryRun(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{
+			"A1": "{{ .region }}", "B1": "{{ .sales }}", "D1": "[table] regions.sql",
+			"A2": "{{ .total }}", "B2": "{{ .missing }}", "D2": "total.sql",
+			"A3": "{{ .none }}", "D3": "none.sql",
+		},
+		map[string]string{
+			"regions.sql": "SELECT region, sales FROM regions WHERE year = :year",
+			"total.sql":   "SELECT total FROM totals",
+			"none.sql":    "SELECT none FROM empty",
+		},
+	)
+	cfg.Params = map[string]any{"year": int64(2026)}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	source := &fakeDataSource{rows: map[string][]map[string]any{
+		"SELECT region, sales FROM regions WHERE year = :year": {
+			{"region": "NORTH", "sales": 10},
+			{"region": "SOUTH", "sales": 20},
+		},
+		"SELECT total FROM totals": {{"total": 30}},
+	}}
+	sources := datasource.NewRegistry(map[string]datasource.DataSource{datasource.DefaultSourceName: source}, logger)
+
+	plan, err := report.NewGenerator(sources, cfg, logger).DryRun(t.Context())
+	require.NoError(t, err)
+
+	_, statErr := os.Stat(cfg.OutputPath)
+	require.ErrorIs(t, statErr, os.ErrNotExist, "dry run must not write the output file")
+
+	require.Len(t, plan.Rows, 3)
+	assert.Equal(t, report.PlanRow{
+		Sheet:   testSheet,
+		Row:     1,
+		SQLFile: "regions.sql",
+		Mode:    "table",
+		Query:   "SELECT region, sales FROM regions WHERE year = :year",
+		Params:  map[string]any{"year": int64(2026)},
+		Records: 2,
+		Cells: []report.PlanCell{
+			{Cell: "A1", Template: "{{ .region }}", Value: "NORTH"},
+			{Cell: "B1", Template: "{{ .sales }}", Value: 10},
+			{Cell: "A2", Template: "{{ .region }}", Value: "SOUTH"},
+			{Cell: "B2", Template: "{{ .sales }}", Value: 20},
+		},
+	}, plan.Rows[0])
+
+	// The table expanded by one row, so the second reference moved down.
+	assert.Equal(t, 3, plan.Rows[1].Row)
+	require.Len(t, plan.Rows[1].Cells, 2)
+	assert.Equal(t, 30, plan.Rows[1].Cells[0].Value)
+	assert.Contains(t, plan.Rows[1].Cells[1].Error, `map has no entry for key "missing"`)
+
+	assert.Equal(t, "query returned no rows", plan.Rows[2].Note)
+	assert.Empty(t, plan.Rows[2].Cells)
+}
+
+func TestGenerateReport_CoercesTemplateOutput(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{
+			"A1": `{{ printf "%.2f" .price }}`, "B1": "{{ .active }} ", "C1": `{{ .id | printf "%05d" }}`,
+			"E1": `{{ printf "%.2f" .price }}`, "F1": "Total: {{ .price }}", "D1": "item.sql",
+		},
+		map[string]string{"item.sql": "SELECT price, active, id FROM items"},
+	)
+
+	// Formatting E1 as text opts it out of coercion.
+	template, err := excelize.OpenFile(cfg.TemplatePath)
+	require.NoError(t, err)
+	textStyle, err := template.NewStyle(&excelize.Style{NumFmt: 49})
+	require.NoError(t, err)
+	require.NoError(t, template.SetCellStyle(testSheet, "E1", "E1", textStyle))
+	require.NoError(t, template.Save())
+	require.NoError(t, template.Close())
+
+	source := &fakeDataSource{rows: map[string][]map[string]any{
+		"SELECT price, active, id FROM items": {{"price": 12.5, "active": true, "id": 42}},
+	}}
+	generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: source})
+
+	f, err := excelize.OpenFile(cfg.OutputPath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	for cell, expectedType := range map[string]excelize.CellType{
+		"A1": excelize.CellTypeUnset, // Numbers carry no type attribute.
+		"B1": excelize.CellTypeBool,
+		"C1": excelize.CellTypeSharedString, // Leading zeros are kept.
+		"E1": excelize.CellTypeSharedString,
+		"F1": excelize.CellTypeSharedString,
+	} {
+		cellType, err := f.GetCellType(testSheet, cell)
+		require.NoError(t, err)
+		assert.Equal(t, expectedType, cellType, "cell %s", cell)
+	}
+
+	value, err := f.GetCellValue(testSheet, "C1")
+	require.NoError(t, err)
+	assert.Equal(t, "00042", value)
+}
+
+func TestGenerateReport_Dates(t *testing.T) {
+	t.Parallel()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	cfg := testWorkspace(t,
+		map[string]any{
+			"A1": "{{ .day }}", "B1": "{{ .created_at }}", "C1": "{{ .day }}", "E1": "{{ .valid_until }}",
+			"F1": "{{ .valid_from }}", "D1": "dates.sql",
+		},
+		map[string]string{"dates.sql": "SELECT day, created_at, valid_until, valid_from FROM contracts"},
+	)
+	cfg.Infinity = report.InfinityDate
+
+	// C1 has a number format of its own, which takes precedence over the default.
+	template, err := excelize.OpenFile(cfg.TemplatePath)
+	require.NoError(t, err)
+	customFormat := "dd.mm.yyyy"
+	customStyle, err := template.NewStyle(&excelize.Style{CustomNumFmt: &customFormat})
+	require.NoError(t, err)
+	require.NoError(t, template.SetCellStyle(testSheet, "C1", "C1", customStyle))
+	require.NoError(t, template.Save())
+	require.NoError(t, template.Close())
+
+	source := &fakeDataSource{rows: map[string][]map[string]any{
+		"SELECT day, created_at, valid_until, valid_from FROM contracts": {{
+			"day":         time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+			"created_at":  time.Date(2024, time.March, 1, 23, 30, 0, 0, berlin), // Written as shown in Berlin.
+			"valid_until": datasource.InfiniteTime{},
+			"valid_from":  datasource.InfiniteTime{Negative: true},
+		}},
+	}}
+	rows := generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: source})
+
+	require.Len(t, rows, 1)
+	assert.Equal(t, []string{"2024-03-01", "2024-03-01 23:30:00", "01.03.2024", "", "9999-12-31"}, rows[0][:5])
+
+	// excelize formats serial 1 as 1899-12-31 when reading, while Excel shows it as 1900-01-01.
+	f, err := excelize.OpenFile(cfg.OutputPath)
+	require.NoError(t, err)
+	defer f.Close()
+	serial, err := f.GetCellValue(testSheet, "F1", excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+	assert.Equal(t, "1", serial)
+}
+
+func TestGenerateReport_InfinityRendering(t *testing.T) {
+	t.Parallel()
+
+	for infinity, expected := range map[string][][]string{
+		"":                   {{"infinity", "-infinity"}},
+		report.InfinityText:  {{"infinity", "-infinity"}},
+		report.InfinityEmpty: {}, // The reference column is cleared as well, leaving no row.
+	} {
+		t.Run(cmp.Or(infinity, "default"), func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testWorkspace(t,
+				map[string]any{"A1": "{{ .valid_until }}", "B1": "{{ .valid_from }}", "D1": "dates.sql"},
+				map[string]string{"dates.sql": "SELECT valid_until, valid_from FROM contracts"},
+			)
+			cfg.Infinity = infinity
+
+			source := &fakeDataSource{rows: map[string][]map[string]any{
+				"SELECT valid_until, valid_from FROM contracts": {{
+					"valid_until": datasource.InfiniteTime{},
+					"valid_from":  datasource.InfiniteTime{Negative: true},
+				}},
+			}}
+			rows := generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: source})
+
+			assert.Equal(t, expected, rows)
+		})
+	}
+}
+
+func TestGenerateReport_Decimals(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{"A1": "{{ .price }}", "B1": "{{ .balance }}", "C1": "{{ add .price .price }}", "D1": "sums.sql"},
+		map[string]string{"sums.sql": "SELECT price, balance FROM accounts"},
+	)
+	cfg.Decimals = report.DecimalsExact
+
+	source := &fakeDataSource{rows: map[string][]map[string]any{
+		"SELECT price, balance FROM accounts": {{
+			"price":   decimal.RequireFromString("19.99"),
+			"balance": decimal.RequireFromString("12345678901234567890.12"), // More digits than a float holds.
+		}},
+	}}
+	rows := generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: source})
+
+	require.Len(t, rows, 1)
+	assert.Equal(t, []string{"19.99", "12345678901234567890.12", "39.98"}, rows[0])
+
+	f, err := excelize.OpenFile(cfg.OutputPath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	for cell, expectedType := range map[string]excelize.CellType{
+		"A1": excelize.CellTypeUnset, // Numbers carry no type attribute.
+		"B1": excelize.CellTypeSharedString,
+		"C1": excelize.CellTypeUnset,
+	} {
+		cellType, err := f.GetCellType(testSheet, cell)
+		require.NoError(t, err)
+		assert.Equal(t, expectedType, cellType, "cell %s", cell)
+	}
+}
+
+func TestGenerateReport_Collections(t *testing.T) {
+	t.Parallel()
+
+	for format, expected := range map[string][]string{
+		"":                             {`["alpha","beta"]`, `{"feature_x":true,"level":10}`, "10", "", "beta"},
+		report.CollectionFormatJSON:    {`["alpha","beta"]`, `{"feature_x":true,"level":10}`, "10", "", "beta"},
+		report.CollectionFormatComma:   {"alpha, beta", "feature_x: true, level: 10", "10", "", "beta"},
+		report.CollectionFormatNewline: {"alpha\nbeta", "feature_x: true\nlevel: 10", "10", "", "beta"},
+	} {
+		t.Run(cmp.Or(format, "default"), func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testWorkspace(t,
+				map[string]any{
+					"A1": "{{ .tag_list }}", "B1": "{{ .settings }}", "C1": "{{ .settings.level }}",
+					"E1": "{{ index .tag_list 1 }}", "D1": "types.sql",
+				},
+				map[string]string{"types.sql": "SELECT tag_list, settings FROM data_types_test"},
+			)
+			cfg.CollectionFormat = format
+
+			source := &fakeDataSource{rows: map[string][]map[string]any{
+				"SELECT tag_list, settings FROM data_types_test": {{
+					"tag_list": []any{"alpha", "beta"},
+					"settings": map[string]any{"feature_x": true, "level": 10.0},
+				}},
+			}}
+			rows := generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: source})
+
+			require.Len(t, rows, 1)
+			assert.Equal(t, expected, rows[0]) // The reference column D is cleared.
+		})
+	}
+}
+
+func TestGenerateReport_ContinueOnError(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{
+			"A1": "{{ .total }}", "D1": "total.sql",
+			"A2": "Sales", "B2": "{{ .sales }}", "D2": "sales.sql",
+			"A3": "{{ .count }}", "D3": "count.sql",
+		},
+		map[string]string{
+			"total.sql": "SELECT total FROM totals",
+			"sales.sql": "SELECT sales FROM s",
+			"count.sql": "SELECT count FROM counts",
+		},
+	)
+	source := &fakeDataSource{rows: map[string][]map[string]any{
+		"SELECT total FROM totals": {{"total": 30}},
+		"SELECT sales FROM s":      {{"sales": 10}, {"sales": 20}},
+		"SELECT count FROM counts": {{"count": 2}},
+	}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sources := datasource.NewRegistry(map[string]datasource.DataSource{datasource.DefaultSourceName: source}, logger)
+
+	t.Run("Abort", func(t *testing.T) {
+		t.Parallel()
+
+		abortCfg := cfg
+		abortCfg.OutputPath = filepath.Join(t.TempDir(), "report.xlsx")
+
+		err := report.NewGenerator(sources, abortCfg, logger).GenerateReport(t.Context())
+
+		require.ErrorIs(t, err, datasource.ErrQueryReturnedMultipleRows)
+		assert.NotErrorIs(t, err, report.ErrReportIncomplete)
+	})
+
+	t.Run("Continue", func(t *testing.T) {
+		t.Parallel()
+
+		continueCfg := cfg
+		continueCfg.OutputPath = filepath.Join(t.TempDir(), "report.xlsx")
+		continueCfg.OnError = report.OnErrorContinue
+		continueCfg.ErrorMarker = "n/a"
+
+		err := report.NewGenerator(sources, continueCfg, logger).GenerateReport(t.Context())
+
+		require.ErrorIs(t, err, report.ErrReportIncomplete)
+		assert.Equal(t, [][]string{{"30"}, {"Sales", "n/a"}, {"2"}}, readSheet(t, continueCfg.OutputPath))
+
+		f, err := excelize.OpenFile(continueCfg.OutputPath)
+		require.NoError(t, err)
+		defer f.Close()
+		errorRows, err := f.GetRows(report.ErrorsSheetName)
+		require.NoError(t, err)
+		require.Len(t, errorRows, 2)
+		assert.Equal(t, []string{"Sheet", "Row", "SQL File", "Error"}, errorRows[0])
+		assert.Equal(t, []string{testSheet, "2", "sales.sql"}, errorRows[1][:3])
+		assert.Contains(t, errorRows[1][3], datasource.ErrQueryReturnedMultipleRows.Error())
+	})
+
+	t.Run("Dry Run", func(t *testing.T) {
+		t.Parallel()
+
+		dryRunCfg := cfg
+		dryRunCfg.OnError = report.OnErrorContinue
+
+		plan, err := report.NewGenerator(sources, dryRunCfg, logger).DryRun(t.Context())
+
+		require.ErrorIs(t, err, report.ErrReportIncomplete)
+		require.Len(t, plan.Failures, 1)
+		assert.Equal(t, 2, plan.Failures[0].Row)
+		assert.Equal(t, "sales.sql", plan.Failures[0].SQLFile)
+	})
+}
+
+func TestGenerateReport_Concurrency(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{
+			"A1": "{{ .region }}", "B1": "{{ .sales }}", "D1": "[table] regions.sql",
+			"A2": "Total", "B2": "{{ .total }}", "D2": "total.sql",
+			"A3": "Count", "B3": "{{ .count }}", "D3": "count.sql",
+		},
+		map[string]string{
+			"regions.sql": "SELECT region, sales FROM regions",
+			"total.sql":   "SELECT total FROM totals",
+			"count.sql":   "SELECT count FROM counts",
+		},
+	)
+	cfg.Concurrency = 3
+	source := &blockingDataSource{fakeDataSource: fakeDataSource{rows: map[string][]map[string]any{
+		"SELECT region, sales FROM regions": {{"region": "NORTH", "sales": 10}, {"region": "SOUTH", "sales": 20}},
+		"SELECT total FROM totals":          {{"total": 30}},
+		"SELECT count FROM counts":          {{"count": 2}},
+	}}}
+	source.barrier.Add(3) // Every query is held until all three run at the same time.
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sources := datasource.NewRegistry(map[string]datasource.DataSource{datasource.DefaultSourceName: source}, logger)
+
+	require.NoError(t, report.NewGenerator(sources, cfg, logger).GenerateReport(ctx))
+
+	assert.Equal(t,
+		[][]string{{"NORTH", "10"}, {"SOUTH", "20"}, {"Total", "30"}, {"Count", "2"}},
+		readSheet(t, cfg.OutputPath),
+	)
+}
+
+func TestGenerateReport_ConcurrencyCancellation(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{
+			"A1": "{{ .total }}", "D1": "total.sql",
+			"A2": "{{ .count }}", "D2": "count.sql",
+		},
+		map[string]string{
+			"total.sql": "SELECT total FROM totals",
+			"count.sql": "SELECT count FROM counts",
+		},
+	)
+	cfg.Concurrency = 2
+	source := &blockingDataSource{}
+	source.barrier.Add(3) // Never released; the queries only return once they are canceled.
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sources := datasource.NewRegistry(map[string]datasource.DataSource{datasource.DefaultSourceName: source}, logger)
+
+	err := report.NewGenerator(sources, cfg, logger).GenerateReport(ctx)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestGenerateReport_DeduplicatesQueries(t *testing.T) {
+	t.Parallel()
+
+	for _, concurrency := range []int{1, 4} {
+		t.Run(fmt.Sprintf("Concurrency %d", concurrency), func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testWorkspace(t,
+				map[string]any{
+					"A1": "{{ .total }}", "D1": "total.sql",
+					"A2": "{{ .region }}", "D2": "[table] regions.sql",
+					"A3": "{{ .total }}", "D3": "total.sql",
+					"A4": "{{ .total }}", "D4": "total_again.sql",
+				},
+				map[string]string{
+					"total.sql":       "SELECT total\nFROM totals",
+					"total_again.sql": "  SELECT total   FROM totals\n",
+					"regions.sql":     "SELECT region FROM regions",
+				},
+			)
+			cfg.Concurrency = concurrency
+			source := &fakeDataSource{rows: map[string][]map[string]any{
+				"SELECT total\nFROM totals":  {{"total": 30}},
+				"SELECT region FROM regions": {{"region": "NORTH"}, {"region": "SOUTH"}},
+			}}
+
+			rows := generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: source})
+
+			assert.Equal(t, [][]string{{"30"}, {"NORTH"}, {"SOUTH"}, {"30"}, {"30"}}, rows)
+			assert.Len(t, source.params, 2, "identical queries must run once")
+		})
+	}
+}
+
+func TestGenerateReport_DiskCache(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{"A1": "{{ .day }}", "B1": "{{ .amount }}", "C1": "{{ .tags }}", "D1": "sales.sql"},
+		map[string]string{"sales.sql": "SELECT day, amount, tags FROM sales WHERE region = :region"},
+	)
+	cfg.Params = map[string]any{"region": "NORTH"}
+	cfg.CacheTTL = time.Hour
+	cfg.CacheDir = filepath.Join(t.TempDir(), "cache")
+	record := map[string]any{
+		"day":    time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		"amount": decimal.RequireFromString("12.5"),
+		"tags":   []any{"a", "b"},
+	}
+	query := "SELECT day, amount, tags FROM sales WHERE region = :region"
+	expected := [][]string{{"2026-10-01", "12.5", `["a","b"]`}}
+
+	first := &fakeDataSource{rows: map[string][]map[string]any{query: {record}}}
+	assert.Equal(t, expected, generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: first}))
+	require.Len(t, first.params, 1)
+
+	// The second run is served from the cache, with the same value types.
+	second := &fakeDataSource{}
+	assert.Equal(t, expected, generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: second}))
+	assert.Empty(t, second.params)
+
+	// Other parameters are a different query.
+	cfg.Params = map[string]any{"region": "SOUTH"}
+	third := &fakeDataSource{rows: map[string][]map[string]any{query: {record}}}
+	generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: third})
+	assert.Len(t, third.params, 1)
+
+	// Expired results are queried again.
+	cfg.Params = map[string]any{"region": "NORTH"}
+	entries, err := os.ReadDir(cfg.CacheDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		expired := time.Now().Add(-2 * time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(cfg.CacheDir, entry.Name()), expired, expired))
+	}
+	fourth := &fakeDataSource{rows: map[string][]map[string]any{query: {record}}}
+	generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: fourth})
+	assert.Len(t, fourth.params, 1)
+}
+
+// snapshottingDataSource serves its own rows when queried directly and the rows of its snapshot through a snapshot.
+type snapshottingDataSource struct {
+	fakeDataSource
+	snapshot *closeRecordingDataSource
+}
+
+func (s *snapshottingDataSource) Snapshot(_ context.Context) (datasource.DataSource, error) {
+	return s.snapshot, nil
+}
+
+type closeRecordingDataSource struct {
+	fakeDataSource
+	closed bool
+}
+
+func (c *closeRecordingDataSource) Close(_ context.Context) error {
+	c.closed = true
+	return nil
+}
+
+func TestGenerateReport_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{"A1": "{{ .total }}", "D1": "total.sql", "A2": "{{ .total }}", "D2": "total_again.sql"},
+		map[string]string{"total.sql": "SELECT total FROM totals", "total_again.sql": "SELECT total FROM totals -- 2"},
+	)
+	cfg.Snapshot = true
+	source := &snapshottingDataSource{
+		fakeDataSource: fakeDataSource{rows: map[string][]map[string]any{
+			"SELECT total FROM totals -- 2": {{"total": 31}}, // Changed after the snapshot was taken.
+		}},
+		snapshot: &closeRecordingDataSource{fakeDataSource: fakeDataSource{rows: map[string][]map[string]any{
+			"SELECT total FROM totals":      {{"total": 30}},
+			"SELECT total FROM totals -- 2": {{"total": 30}},
+		}}},
+	}
+
+	rows := generate(t, cfg, map[string]datasource.DataSource{datasource.DefaultSourceName: source})
+
+	assert.Equal(t, [][]string{{"30"}, {"30"}}, rows)
+	assert.Empty(t, source.params, "all queries run against the snapshot")
+	assert.True(t, source.snapshot.closed, "the snapshot is released")
+}
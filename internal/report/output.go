@@ -0,0 +1,69 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// NowTemplateKey is the key under which the time of the run is exposed to output path templates, e.g.
+// `{{ .now | date "2006-01" }}`.
+const NowTemplateKey = "now"
+
+// IsOutputPathTemplate reports whether an output path contains template actions that RenderOutputPath replaces.
+func IsOutputPathTemplate(path string) bool {
+	return strings.Contains(path, "{{")
+}
+
+// RenderOutputPath evaluates an output path as a text/template with the functions of cell templates, so that reports
+// can be archived by date and parameters, e.g. `reports/{{ .now | date "2006-01" }}_{{ .params.region }}.xlsx`. The
+// template sees the report parameters under ParamsTemplateKey and now under NowTemplateKey. The rendered path must stay
+// in the directory written before the first template action, "reports" in the example, so that parameter values like
+// "../../etc/x" can't write elsewhere. Paths without template actions are returned unchanged.
+func RenderOutputPath(path string, params map[string]any, now time.Time) (string, error) {
+	if !IsOutputPathTemplate(path) {
+		return path, nil
+	}
+
+	tmpl, err := template.New("output_path").
+		Option("missingkey=error").
+		Funcs(templateFuncs()).
+		Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("parse output path template: %w", err)
+	}
+
+	if params == nil {
+		params = map[string]any{}
+	}
+	data := map[string]any{ParamsTemplateKey: params, NowTemplateKey: now}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("render output path template: %w", err)
+	}
+	rendered := strings.TrimSpace(sb.String())
+	if rendered == "" {
+		return "", errors.New("output path template rendered an empty path")
+	}
+	baseDir := filepath.Dir(strings.TrimSpace(path[:strings.Index(path, "{{")]))
+	if rel, err := filepath.Rel(baseDir, rendered); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("output path template rendered %q, which is outside of %q", rendered, baseDir)
+	}
+
+	return rendered, nil
+}
+
+// RenderOutputPath renders the output path with the report parameters and now, taken in the report's time zone or the
+// local one if none is configured, so that file names follow the calendar of whoever reads them. See RenderOutputPath.
+func (c Config) RenderOutputPath(now time.Time) (string, error) {
+	if c.Timezone != "" {
+		if loc, err := time.LoadLocation(c.Timezone); err == nil { // An invalid zone is reported by Valid.
+			now = now.In(loc)
+		}
+	}
+	return RenderOutputPath(c.OutputPath, c.Params, now)
+}
@@ -0,0 +1,81 @@
+package report_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+func TestRenderOutputPath(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	params := map[string]any{"region": "NORTH", "month": time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name    string
+		path    string
+		params  map[string]any
+		want    string
+		wantErr string
+	}{
+		{name: "Static Path", path: "reports/{sales}.xlsx", want: "reports/{sales}.xlsx"},
+		{
+			name:   "Date And Param",
+			path:   `reports/sales_{{ .now | date "2006-01" }}_{{ .params.region }}.xlsx`,
+			params: params,
+			want:   "reports/sales_2026-10_NORTH.xlsx",
+		},
+		{
+			name:   "Date Param",
+			path:   `sales_{{ .params.month | date "2006-01" }}.xlsx`,
+			params: params,
+			want:   "sales_2026-09.xlsx",
+		},
+		{name: "Undefined Param", path: "{{ .params.region }}.xlsx", wantErr: "render output path template"},
+		{name: "Syntax Error", path: "{{ .params.region .xlsx", params: params, wantErr: "parse output path template"},
+		{name: "Empty Result", path: `{{ "" }}`, wantErr: "empty path"},
+		{
+			name:   "Separators In Date Layout",
+			path:   `/srv/reports/{{ .now | date "2006/01" }}/{{ .params.region }}.xlsx`,
+			params: params,
+			want:   "/srv/reports/2026/10/NORTH.xlsx",
+		},
+		{
+			name:    "Param Leaving Directory",
+			path:    "reports/{{ .params.region }}.xlsx",
+			params:  map[string]any{"region": "../../etc/x"},
+			wantErr: "outside of \"reports\"",
+		},
+		{
+			name:    "Param Leaving Working Directory",
+			path:    "{{ .params.region }}.xlsx",
+			params:  map[string]any{"region": "../x"},
+			wantErr: "outside of \".\"",
+		},
+		{
+			name:    "Absolute Param",
+			path:    "{{ .params.region }}.xlsx",
+			params:  map[string]any{"region": "/etc/x"},
+			wantErr: "outside of",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := report.RenderOutputPath(tt.path, tt.params, now)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
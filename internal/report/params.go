@@ -0,0 +1,133 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParamsTemplateKey is the key under which report parameters are exposed to cell templates, e.g.
+// "{{ .params.region }}".
+const ParamsTemplateKey = "params"
+
+// ParamDateLayout is the layout of date parameter values.
+const ParamDateLayout = time.DateOnly
+
+// ParamType is the type a report parameter value is parsed into.
+type ParamType string
+
+const (
+	ParamTypeString ParamType = "string" // Default; the value is used verbatim.
+	ParamTypeInt    ParamType = "int"    // 64-bit signed integer.
+	ParamTypeFloat  ParamType = "float"  // 64-bit floating point number.
+	ParamTypeBool   ParamType = "bool"   // Anything accepted by strconv.ParseBool.
+	ParamTypeDate   ParamType = "date"   // Date in ParamDateLayout (YYYY-MM-DD), as midnight UTC.
+)
+
+// ErrInvalidParam indicates a malformed report parameter specification or value.
+var ErrInvalidParam = errors.New("invalid report parameter")
+
+// paramNameRegex matches parameter names, which must be usable both as ":name" in SQL and as ".name" in templates.
+var paramNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// IsValidParamName reports whether name can be used as a report parameter name.
+func IsValidParamName(name string) bool {
+	return paramNameRegex.MatchString(name)
+}
+
+// ParseParams parses parameter specifications into a map of names to typed values. See ParseParam for the format.
+// Specifying the same parameter more than once is an error.
+func ParseParams(specs []string) (map[string]any, error) {
+	params := make(map[string]any, len(specs))
+	for _, spec := range specs {
+		name, value, err := ParseParam(spec)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := params[name]; exists {
+			return nil, fmt.Errorf("%w: %q specified more than once", ErrInvalidParam, name)
+		}
+		params[name] = value
+	}
+
+	return params, nil
+}
+
+// ParseParam parses a single parameter specification of the form "name=value" or "name:type=value", where type is one
+// of string (default), int, float, bool or date, e.g. "region=NORTH" or "month:date=2026-10-01".
+func ParseParam(spec string) (string, any, error) {
+	key, raw, found := strings.Cut(spec, "=")
+	if !found {
+		return "", nil, fmt.Errorf("%w: %q must have the form name[:type]=value", ErrInvalidParam, spec)
+	}
+
+	name, typeName, _ := strings.Cut(strings.TrimSpace(key), ":")
+	if !IsValidParamName(name) {
+		return "", nil, fmt.Errorf(
+			"%w: name %q must start with a letter or underscore and contain only letters, digits and underscores",
+			ErrInvalidParam,
+			name,
+		)
+	}
+
+	paramType := ParamTypeString
+	if typeName != "" {
+		paramType = ParamType(strings.ToLower(typeName))
+	}
+
+	value, err := parseParamValue(paramType, raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w %q: %w", ErrInvalidParam, name, err)
+	}
+
+	return name, value, nil
+}
+
+func parseParamValue(paramType ParamType, raw string) (any, error) {
+	switch paramType {
+	case ParamTypeString:
+		return raw, nil
+	case ParamTypeInt:
+		value, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not an int", raw)
+		}
+		return value, nil
+	case ParamTypeFloat:
+		value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a float", raw)
+		}
+		return value, nil
+	case ParamTypeBool:
+		value, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a bool", raw)
+		}
+		return value, nil
+	case ParamTypeDate:
+		value, err := time.Parse(ParamDateLayout, strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a date in the format %s", raw, ParamDateLayout)
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf(
+			"unsupported type %q, expected one of %s, %s, %s, %s or %s",
+			paramType, ParamTypeString, ParamTypeInt, ParamTypeFloat, ParamTypeBool, ParamTypeDate,
+		)
+	}
+}
+
+// isSupportedParamValue reports whether a parameter value has one of the types produced by ParseParam.
+func isSupportedParamValue(value any) bool {
+	switch value.(type) {
+	case string, int64, float64, bool, time.Time:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,65 @@
+package report_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+func TestParseParam(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		spec      string
+		wantName  string
+		wantValue any
+		wantErr   string
+	}{
+		{spec: "region=NORTH", wantName: "region", wantValue: "NORTH"},
+		{spec: "note=a=b", wantName: "note", wantValue: "a=b"},
+		{spec: "empty=", wantName: "empty", wantValue: ""},
+		{spec: "region:string= NORTH ", wantName: "region", wantValue: " NORTH "},
+		{spec: "limit:int=25", wantName: "limit", wantValue: int64(25)},
+		{spec: "ratio:float=0.5", wantName: "ratio", wantValue: 0.5},
+		{spec: "active:BOOL=true", wantName: "active", wantValue: true},
+		{spec: "month:date=2026-10-01", wantName: "month", wantValue: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "region", wantErr: "must have the form"},
+		{spec: "1region=x", wantErr: "must start with a letter"},
+		{spec: "limit:int=ten", wantErr: `value "ten" is not an int`},
+		{spec: "month:date=01.10.2026", wantErr: "is not a date"},
+		{spec: "at:time=10:00", wantErr: `unsupported type "time"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			t.Parallel()
+
+			name, value, err := report.ParseParam(tt.spec)
+			if tt.wantErr != "" {
+				require.ErrorIs(t, err, report.ErrInvalidParam)
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantValue, value)
+		})
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	t.Parallel()
+
+	params, err := report.ParseParams([]string{"region=NORTH", "year:int=2026"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"region": "NORTH", "year": int64(2026)}, params)
+
+	_, err = report.ParseParams([]string{"region=NORTH", "region=SOUTH"})
+	require.ErrorIs(t, err, report.ErrInvalidParam)
+	assert.ErrorContains(t, err, "more than once")
+}
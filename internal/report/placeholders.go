@@ -0,0 +1,105 @@
+package report
+
+import (
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// templateFields returns the names of the top-level data fields a parsed cell template references, in order of
+// appearance, e.g. "price" for `{{ .price | printf "%.2f" }}` or `{{ $.price }}`. Fields inside range and with blocks
+// are skipped, since dot refers to something other than the row data there.
+func templateFields(tmpl *template.Template) []string {
+	var fields []string
+	if tmpl.Tree != nil {
+		collectTemplateFields(tmpl.Tree.Root, &fields)
+	}
+	return fields
+}
+
+func collectTemplateFields(node parse.Node, fields *[]string) {
+	add := func(name string) {
+		if !slices.Contains(*fields, name) {
+			*fields = append(*fields, name)
+		}
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectTemplateFields(child, fields)
+		}
+	case *parse.ActionNode:
+		collectTemplateFields(n.Pipe, fields)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectTemplateFields(cmd, fields)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectTemplateFields(arg, fields)
+		}
+	case *parse.ChainNode:
+		collectTemplateFields(n.Node, fields)
+	case *parse.FieldNode:
+		add(n.Ident[0])
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			add(n.Ident[1])
+		}
+	case *parse.IfNode:
+		collectTemplateFields(n.Pipe, fields)
+		collectTemplateFields(n.List, fields)
+		collectTemplateFields(n.ElseList, fields)
+	case *parse.RangeNode:
+		collectTemplateFields(n.Pipe, fields)
+		collectTemplateFields(n.ElseList, fields)
+	case *parse.WithNode:
+		collectTemplateFields(n.Pipe, fields)
+		collectTemplateFields(n.ElseList, fields)
+	}
+}
+
+// nearestName returns the candidate closest to name by case-insensitive edit distance, or an empty string if there
+// are no candidates.
+func nearestName(name string, candidates []string) string {
+	nearest, bestDistance := "", -1
+	for _, candidate := range candidates {
+		distance := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		if bestDistance < 0 || distance < bestDistance {
+			nearest, bestDistance = candidate, distance
+		}
+	}
+	return nearest
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
@@ -0,0 +1,152 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+// Formats a Plan can be written in.
+const (
+	PlanFormatText = "text"
+	PlanFormatJSON = "json"
+)
+
+// ErrUnsupportedPlanFormat indicates a plan format other than PlanFormatText or PlanFormatJSON.
+var ErrUnsupportedPlanFormat = errors.New("unsupported plan format")
+
+// Plan describes what generating a report would do: which rows reference which SQL files, the queries that run and
+// the value every placeholder receives. It is produced by Generator.DryRun.
+type Plan struct {
+	TemplatePath string    `json:"template_path"`
+	OutputPath   string    `json:"output_path"` // Where the report would be written; never touched by a dry run.
+	Rows         []PlanRow `json:"rows"`
+
+	Failures []RowFailure `json:"failures,omitempty"` // Rows that failed with OnErrorContinue.
+}
+
+// PlanRow is a template row with a reference to an SQL file.
+type PlanRow struct {
+	Sheet      string         `json:"sheet"`
+	Row        int            `json:"row"` // Row in the generated report; rows shift when table references expand.
+	SQLFile    string         `json:"sql_file"`
+	DataSource string         `json:"data_source"` // Empty for the default data source.
+	Mode       string         `json:"mode"`
+	Query      string         `json:"query,omitempty"`
+	Params     map[string]any `json:"params,omitempty"` // Report parameters the query uses.
+	Records    int            `json:"records"`          // Number of records the query returned.
+	Note       string         `json:"note,omitempty"`   // Why the row is skipped, if it is.
+	Cells      []PlanCell     `json:"cells,omitempty"`
+}
+
+// PlanCell is a cell whose template would be replaced.
+type PlanCell struct {
+	Cell     string `json:"cell"`
+	Template string `json:"template"`
+	Value    any    `json:"value,omitempty"`
+	Error    string `json:"error,omitempty"` // Set if the template fails; the cell would keep its template.
+}
+
+// setQuery records the query of the row and the report parameters it uses. It is a no-op on a nil row.
+func (r *PlanRow) setQuery(query string, params map[string]any) {
+	if r == nil {
+		return
+	}
+	r.Query = query
+	for _, name := range datasource.QueryParamNames(query) {
+		if r.Params == nil {
+			r.Params = make(map[string]any)
+		}
+		r.Params[name] = params[name]
+	}
+}
+
+// setRecords records the number of records the query returned. It is a no-op on a nil row.
+func (r *PlanRow) setRecords(count int) {
+	if r == nil {
+		return
+	}
+	r.Records = count
+}
+
+// skip records why the row's placeholders aren't replaced. It is a no-op on a nil row.
+func (r *PlanRow) skip(reason string) {
+	if r == nil {
+		return
+	}
+	r.Note = reason
+}
+
+// addCell records a resolved cell. It is a no-op on a nil row, which is what the generator uses outside dry runs.
+func (r *PlanRow) addCell(cell PlanCell) {
+	if r == nil {
+		return
+	}
+	r.Cells = append(r.Cells, cell)
+}
+
+// WritePlan writes the plan to w in the given format.
+func WritePlan(w io.Writer, plan Plan, format string) error {
+	var content []byte
+	switch format {
+	case PlanFormatText:
+		content = []byte(plan.text())
+	case PlanFormatJSON:
+		var err error
+		if content, err = json.MarshalIndent(plan, "", "  "); err != nil {
+			return fmt.Errorf("encode plan as JSON: %w", err)
+		}
+		content = append(content, '\n')
+	default:
+		return fmt.Errorf("%w %q: expected %q or %q", ErrUnsupportedPlanFormat, format, PlanFormatText, PlanFormatJSON)
+	}
+
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("write plan: %w", err)
+	}
+	return nil
+}
+
+// text renders the plan in a human-readable form, one block per row.
+func (p Plan) text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Template: %s\nOutput:   %s (not written)\n", p.TemplatePath, p.OutputPath)
+
+	for _, row := range p.Rows {
+		source := row.DataSource
+		if source == "" {
+			source = "default"
+		}
+		fmt.Fprintf(&sb, "\n%s!%d: %s on %q (%s)\n", row.Sheet, row.Row, row.SQLFile, source, row.Mode)
+		if row.Query != "" {
+			fmt.Fprintf(&sb, "  query:   %s\n", strings.Join(strings.Fields(row.Query), " "))
+		}
+		for _, name := range slices.Sorted(maps.Keys(row.Params)) {
+			fmt.Fprintf(&sb, "  param:   %s = %v\n", name, row.Params[name])
+		}
+		fmt.Fprintf(&sb, "  records: %d\n", row.Records)
+		if row.Note != "" {
+			fmt.Fprintf(&sb, "  note:    %s\n", row.Note)
+		}
+		for _, cell := range row.Cells {
+			if cell.Error != "" {
+				fmt.Fprintf(&sb, "  %s: %s -> error: %s\n", cell.Cell, cell.Template, cell.Error)
+				continue
+			}
+			fmt.Fprintf(&sb, "  %s: %s -> %v\n", cell.Cell, cell.Template, cell.Value)
+		}
+	}
+
+	for _, failure := range p.Failures {
+		fmt.Fprintf(&sb, "\n%s!%d: %s failed: %s\n", failure.Sheet, failure.Row, failure.SQLFile, failure.Error)
+	}
+
+	fmt.Fprintf(&sb, "\n%d row(s) with references\n", len(p.Rows))
+	return sb.String()
+}
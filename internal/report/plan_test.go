@@ -0,0 +1,83 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+func TestWritePlan(t *testing.T) {
+	t.Parallel()
+
+	plan := report.Plan{
+		TemplatePath: "/reports/template.xlsx",
+		OutputPath:   "/reports/out.xlsx",
+		Rows: []report.PlanRow{
+			{
+				Sheet:      "Sales",
+				Row:        4,
+				SQLFile:    "sales.sql",
+				DataSource: "warehouse",
+				Mode:       "single",
+				Query:      "SELECT total\nFROM sales\nWHERE year = :year",
+				Params:     map[string]any{"year": 2026},
+				Records:    1,
+				Cells: []report.PlanCell{
+					{Cell: "B4", Template: "{{ .total }}", Value: 42},
+					{Cell: "C4", Template: "{{ .totl }}", Error: "no entry for key"},
+				},
+			},
+			{Sheet: "Sales", Row: 6, SQLFile: "empty.sql", Mode: "single", Note: "SQL file is empty"},
+		},
+	}
+
+	t.Run("Text", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		require.NoError(t, report.WritePlan(&buf, plan, report.PlanFormatText))
+		assert.Equal(t, `Template: /reports/template.xlsx
+Output:   /reports/out.xlsx (not written)
+
+Sales!4: sales.sql on "warehouse" (single)
+  query:   SELECT total FROM sales WHERE year = :year
+  param:   year = 2026
+  records: 1
+  B4: {{ .total }} -> 42
+  C4: {{ .totl }} -> error: no entry for key
+
+Sales!6: empty.sql on "default" (single)
+  records: 0
+  note:    SQL file is empty
+
+2 row(s) with references
+`, buf.String())
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		require.NoError(t, report.WritePlan(&buf, plan, report.PlanFormatJSON))
+
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, "/reports/out.xlsx", decoded["output_path"])
+		rows, ok := decoded["rows"].([]any)
+		require.True(t, ok)
+		require.Len(t, rows, 2)
+		assert.Equal(t, "sales.sql", rows[0].(map[string]any)["sql_file"])
+	})
+
+	t.Run("Unsupported Format", func(t *testing.T) {
+		t.Parallel()
+
+		err := report.WritePlan(&bytes.Buffer{}, plan, "yaml")
+		require.ErrorIs(t, err, report.ErrUnsupportedPlanFormat)
+	})
+}
@@ -0,0 +1,201 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+// pendingQuery is a query of the current run. Identical queries share one pendingQuery, so that they run only once, and
+// with a Concurrency above one it is started ahead of the rows referencing it, so that queries run concurrently while
+// the workbook is still written by a single goroutine.
+type pendingQuery struct {
+	source datasource.DataSource
+	query  string
+	table  bool       // Fetch all records instead of a single one.
+	key    string     // See queryCacheKey.
+	cache  *diskCache // Nil unless results are cached on disk.
+
+	done    chan struct{} // Closed once the query finished.
+	record  map[string]any
+	records []map[string]any
+	err     error
+}
+
+// pendingKey identifies a referenced row by its position in the template, before table references shifted it.
+type pendingKey struct {
+	sheet string
+	row   int // 1-based
+}
+
+func (p *pendingQuery) run(ctx context.Context, params map[string]any) {
+	defer close(p.done)
+
+	if p.cache != nil {
+		if result, ok := p.cache.load(p.key); ok {
+			p.record, p.records = result.Record, result.Records
+			return
+		}
+	}
+
+	if p.table {
+		p.records, p.err = p.source.FetchRows(ctx, p.query, params)
+	} else {
+		p.record, p.err = p.source.FetchData(ctx, p.query, params)
+	}
+
+	if p.cache != nil && p.err == nil {
+		p.cache.store(p.key, cachedResult{Record: p.record, Records: p.records})
+	}
+}
+
+// wait blocks until the query finished or ctx is done, whichever happens first.
+func (p *pendingQuery) wait(ctx context.Context) error {
+	select {
+	case <-p.done:
+		return p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// query returns the pendingQuery of the given query, and whether it is new and still has to be run. Queries that were
+// requested before in this run are returned as they are, finished or not.
+func (g *Generator) query(
+	sourceName string,
+	source datasource.DataSource,
+	table bool,
+	query string,
+) (*pendingQuery, bool) {
+	key := g.queryCacheKey(sourceName, table, query)
+	if p, ok := g.queries[key]; ok {
+		return p, false
+	}
+
+	p := &pendingQuery{
+		source: source,
+		query:  query,
+		table:  table,
+		key:    key,
+		cache:  g.diskCache,
+		done:   make(chan struct{}),
+	}
+	g.queries[key] = p
+	return p, true
+}
+
+// runQuery returns the pendingQuery of the given query, running it first unless it was requested before in this run.
+func (g *Generator) runQuery(
+	ctx context.Context,
+	sourceName string,
+	source datasource.DataSource,
+	table bool,
+	query string,
+) *pendingQuery {
+	p, isNew := g.query(sourceName, source, table, query)
+	if isNew {
+		p.run(ctx, g.config.Params)
+	}
+	return p
+}
+
+// startQueries starts the query of every referenced row of the given sheets on a pool of g.config.Concurrency workers,
+// in template order, and returns them by row. Identical queries are started once. Rows that fail or are skipped before
+// their query would run, like rows with a missing or empty SQL file, are left out; processRow reports them as usual.
+// The returned stop function cancels the queries that are still running and waits for the workers to exit.
+func (g *Generator) startQueries(
+	ctx context.Context,
+	file *excelize.File,
+	sheetList []string,
+	zeroBasedSQLColIndex int,
+) (map[pendingKey]*pendingQuery, func(), error) {
+	pending := make(map[pendingKey]*pendingQuery)
+	var queue []*pendingQuery
+
+	for _, sheetName := range sheetList {
+		rows, err := file.GetRows(sheetName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get rows from sheet %q: %w", sheetName, err)
+		}
+
+		for rowIndex, rowCells := range rows {
+			if len(rowCells) <= zeroBasedSQLColIndex {
+				continue
+			}
+			ref := parseReference(rowCells[zeroBasedSQLColIndex])
+			if ref.Path == "" {
+				continue
+			}
+
+			queryBytes, err := g.readQueryFile(ref.Path)
+			query := strings.TrimSpace(string(queryBytes))
+			if err != nil || query == "" {
+				continue
+			}
+			source, err := g.sources.Lookup(ref.Source)
+			if err != nil {
+				continue
+			}
+
+			p, isNew := g.query(ref.Source, source, ref.Mode == referenceModeTable, query)
+			pending[pendingKey{sheet: sheetName, row: rowIndex + 1}] = p
+			if isNew {
+				queue = append(queue, p)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	jobs := make(chan *pendingQuery)
+	var wg sync.WaitGroup
+
+	for range min(g.config.Concurrency, len(queue)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				p.run(ctx, g.config.Params)
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for _, p := range queue {
+			select {
+			case jobs <- p:
+			case <-ctx.Done():
+				return // Queries that weren't started are never waited for; the caller is done as well.
+			}
+		}
+	}()
+
+	stop := func() {
+		cancel()
+		wg.Wait()
+	}
+	return pending, stop, nil
+}
+
+// fetchData waits for the result of a single-row query.
+func fetchData(ctx context.Context, pending *pendingQuery) (map[string]any, error) {
+	if err := pending.wait(ctx); err != nil {
+		return nil, err
+	}
+	return pending.record, nil
+}
+
+// fetchRows waits for the result of a table query.
+func fetchRows(ctx context.Context, pending *pendingQuery) ([]map[string]any, error) {
+	if err := pending.wait(ctx); err != nil {
+		return nil, err
+	}
+	return pending.records, nil
+}
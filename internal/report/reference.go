@@ -0,0 +1,58 @@
+package report
+
+import (
+	"strings"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+// referenceMode determines how the result of a referenced query is applied to its template row.
+type referenceMode int
+
+const (
+	// referenceModeSingle expects exactly one result record, which fills the placeholders of the row itself.
+	referenceModeSingle referenceMode = iota
+	// referenceModeTable treats the row as a prototype that is repeated once for every result record.
+	referenceModeTable
+)
+
+// tableModeTag marks a reference as a table reference, e.g. "[table] sales/by_region.sql".
+const tableModeTag = "[table]"
+
+func (m referenceMode) String() string {
+	switch m {
+	case referenceModeTable:
+		return "table"
+	case referenceModeSingle:
+		fallthrough
+	default:
+		return "single"
+	}
+}
+
+// reference is the parsed content of a cell in the data source reference column. Its format is
+// "[table] source:path/to/query.sql", where both the table tag and the source prefix are optional.
+type reference struct {
+	Mode   referenceMode
+	Source string // Name of the data source to query; empty selects the default data source.
+	Path   string // SQL file path relative to the queries directory.
+}
+
+// parseReference parses the raw content of a reference cell. An empty path means the row has no reference.
+func parseReference(raw string) reference {
+	ref := reference{Mode: referenceModeSingle}
+	value := strings.TrimSpace(raw)
+
+	if len(value) >= len(tableModeTag) && strings.EqualFold(value[:len(tableModeTag)], tableModeTag) {
+		ref.Mode = referenceModeTable
+		value = strings.TrimSpace(value[len(tableModeTag):])
+	}
+
+	if source, path, found := strings.Cut(value, ":"); found && datasource.IsValidSourceName(source) {
+		ref.Source = source
+		value = strings.TrimSpace(path)
+	}
+
+	ref.Path = value
+	return ref
+}
@@ -0,0 +1,318 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/nikoksr/assert-go"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+)
+
+// Problem is an issue found while validating a template, located by sheet and cell.
+type Problem struct {
+	Sheet   string // Empty for problems concerning the whole report, like the burst driver query.
+	Cell    string // Empty for problems concerning the whole sheet.
+	SQLFile string // Referenced SQL file the problem relates to; only set for schema problems.
+	Message string
+}
+
+func (p Problem) String() string {
+	location := p.Sheet
+	if p.Cell != "" {
+		location += "!" + p.Cell
+	}
+	if p.SQLFile != "" {
+		location += " (" + p.SQLFile + ")"
+	}
+	if location == "" {
+		return p.Message
+	}
+	return location + ": " + p.Message
+}
+
+// ValidateOptions enables optional checks of ValidateTemplate.
+type ValidateOptions struct {
+	// SourceNames, if not nil, are the names of the configured data sources that references must resolve to.
+	SourceNames []string
+	// Sources, if not nil, enables schema checks: every query is prepared, but not executed, against its data source
+	// and the placeholders of its row are checked against the result columns. Implies the data source name check.
+	Sources *datasource.Registry
+}
+
+// ValidateTemplate checks a report template. For every row with a reference it checks that the SQL file exists inside
+// the queries directory, that the query only uses defined parameters and that every cell template in the row parses.
+// With a BurstQuery, the driver query is checked as well, and parameters it may provide aren't reported as undefined.
+// Data sources are only contacted for the schema checks enabled by the options. All problems are returned in sheet,
+// row and column order; the error is only non-nil if the template can't be read at all.
+func ValidateTemplate(ctx context.Context, cfg Config, opts ValidateOptions, logger *slog.Logger) ([]Problem, error) {
+	assert.Assert(ctx != nil, "context must not be nil")
+	assert.Assert(logger != nil, "Logger must not be nil")
+	assert.Assert(filepath.IsAbs(cfg.TemplatePath), "template path must be absolute")
+	assert.Assert(filepath.IsAbs(cfg.QueriesDir), "queries directory must be absolute")
+
+	logger = logger.With(slog.String("component", "TemplateValidator"))
+	logger.Info("Validating template", slog.String("template", cfg.TemplatePath))
+
+	file, err := excelize.OpenFile(cfg.TemplatePath)
+	if err != nil {
+		return nil, fmt.Errorf("open template file %q: %w", cfg.TemplatePath, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			logger.Warn("Error closing template file", slog.String("error", closeErr.Error()))
+		}
+	}()
+
+	sqlColNum, err := excelize.ColumnNameToNumber(cfg.DataSourceRefColumn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DataSourceRefCol %q: %w", cfg.DataSourceRefColumn, err)
+	}
+
+	validator := &templateValidator{
+		config:               cfg,
+		sourceNames:          opts.SourceNames,
+		sources:              opts.Sources,
+		zeroBasedSQLColIndex: sqlColNum - 1,
+		described:            make(map[string]describeResult),
+		logger:               logger,
+	}
+	if opts.Sources != nil {
+		validator.sourceNames = opts.Sources.Names()
+	}
+
+	var problems []Problem
+	if cfg.BurstQuery != "" {
+		ref := parseReference(cfg.BurstQuery)
+		if _, message := validator.validateQueryFile(ref.Path); message != "" {
+			problems = append(problems, Problem{Message: "burst driver: " + message})
+		}
+	}
+	for _, sheetName := range file.GetSheetList() {
+		rows, err := file.GetRows(sheetName)
+		if err != nil {
+			problems = append(problems, Problem{Sheet: sheetName, Message: fmt.Sprintf("read rows: %v", err)})
+			continue
+		}
+
+		for rowIndex, rowCells := range rows {
+			if err := ctx.Err(); err != nil {
+				return problems, fmt.Errorf("validation interrupted on sheet %q: %w", sheetName, err)
+			}
+			problems = append(problems, validator.validateRow(ctx, sheetName, rowIndex+1, rowCells)...)
+		}
+	}
+
+	logger.Info("Finished validating template", slog.Int("problem_count", len(problems)))
+	return problems, nil
+}
+
+// templateValidator holds the settings and state needed to validate the rows of a template.
+type templateValidator struct {
+	config               Config
+	sourceNames          []string
+	sources              *datasource.Registry // Nil unless schema checks are enabled.
+	zeroBasedSQLColIndex int
+	described            map[string]describeResult // Query descriptions by data source and SQL file.
+	logger               *slog.Logger
+}
+
+// describeResult is the cached outcome of describing a referenced query. Columns is nil if the data source doesn't
+// support describing queries.
+type describeResult struct {
+	columns []datasource.Column
+	err     error
+}
+
+// validateRow validates a single template row. Rows without a reference have nothing to validate, since their cells
+// are never processed.
+func (v *templateValidator) validateRow(
+	ctx context.Context,
+	sheetName string,
+	excelRowIndex int,
+	rowCells []string,
+) []Problem {
+	if len(rowCells) <= v.zeroBasedSQLColIndex {
+		return nil
+	}
+	ref := parseReference(rowCells[v.zeroBasedSQLColIndex])
+	if ref.Path == "" {
+		return nil
+	}
+
+	refCell, _ := excelize.CoordinatesToCellName(v.zeroBasedSQLColIndex+1, excelRowIndex)
+	var problems []Problem
+	addProblem := func(cell, format string, args ...any) {
+		problems = append(problems, Problem{Sheet: sheetName, Cell: cell, Message: fmt.Sprintf(format, args...)})
+	}
+
+	knownSource := v.sourceNames == nil || v.isKnownSource(ref.Source)
+	if !knownSource {
+		if ref.Source == "" {
+			addProblem(refCell, "reference has no data source prefix but no default data source is configured")
+		} else {
+			addProblem(refCell, "unknown data source %q", ref.Source)
+		}
+	}
+
+	query, message := v.validateQueryFile(ref.Path)
+	if message != "" {
+		addProblem(refCell, "%s", message)
+	}
+
+	// Schema checks need a readable query and a data source to prepare it against.
+	var columns []datasource.Column
+	if v.sources != nil && knownSource && query != "" {
+		result := v.describe(ctx, ref, query)
+		if result.err != nil {
+			problems = append(problems, Problem{
+				Sheet:   sheetName,
+				Cell:    refCell,
+				SQLFile: ref.Path,
+				Message: fmt.Sprintf("prepare query: %v", result.err),
+			})
+		}
+		columns = result.columns
+	}
+
+	for cellIndex, cellValue := range rowCells {
+		if cellIndex == v.zeroBasedSQLColIndex || !strings.Contains(cellValue, "{{") {
+			continue
+		}
+		cell, _ := excelize.CoordinatesToCellName(cellIndex+1, excelRowIndex)
+		tmpl, err := parseCellTemplate(cellValue)
+		if err != nil {
+			addProblem(cell, "invalid template: %v", err)
+			continue
+		}
+		if columns != nil {
+			problems = append(problems, checkPlaceholders(tmpl, columns, sheetName, cell, ref.Path)...)
+		}
+	}
+
+	return problems
+}
+
+// describe returns the result columns of a referenced query, preparing it at most once per data source and SQL file.
+func (v *templateValidator) describe(ctx context.Context, ref reference, query string) describeResult {
+	key := ref.Source + ":" + ref.Path
+	if result, ok := v.described[key]; ok {
+		return result
+	}
+
+	var result describeResult
+	source, err := v.sources.Lookup(ref.Source)
+	if err != nil {
+		result.err = err
+	} else if describer, ok := source.(datasource.Describer); ok {
+		result.columns, result.err = describer.DescribeQuery(ctx, query, v.config.Params)
+	} else {
+		v.logger.Warn(
+			"Data source doesn't support describing queries, skipping schema checks",
+			slog.String("data_source", ref.Source),
+			slog.String("sql_file", ref.Path),
+		)
+	}
+
+	v.described[key] = result
+	return result
+}
+
+// checkPlaceholders reports every field of a cell template that doesn't match a result column, suggesting the nearest
+// column name. The report parameters are available in every row and are not checked.
+func checkPlaceholders(
+	tmpl *template.Template,
+	columns []datasource.Column,
+	sheetName, cell, sqlFile string,
+) []Problem {
+	columnNames := make([]string, 0, len(columns))
+	for _, column := range columns {
+		columnNames = append(columnNames, column.Name)
+	}
+
+	var problems []Problem
+	for _, field := range templateFields(tmpl) {
+		if field == ParamsTemplateKey || slices.Contains(columnNames, field) {
+			continue
+		}
+
+		message := fmt.Sprintf("placeholder %q matches no result column", field)
+		if nearest := nearestName(field, columnNames); nearest != "" {
+			message += fmt.Sprintf("; did you mean %q?", nearest)
+		}
+		problems = append(problems, Problem{Sheet: sheetName, Cell: cell, SQLFile: sqlFile, Message: message})
+	}
+
+	return problems
+}
+
+// validateQueryFile checks that the referenced SQL file exists inside the queries directory and that its parameters
+// are defined. It returns the query and a description of the first problem found, or an empty string.
+func (v *templateValidator) validateQueryFile(relativePath string) (string, string) {
+	// Resolved like during generation, so that validation accepts exactly the references generation can read.
+	name, err := resolveQueryPath(relativePath)
+	if err != nil {
+		return "", fmt.Sprintf("SQL file %q is outside the queries directory %q", relativePath, v.config.QueriesDir)
+	}
+	queriesFS := os.DirFS(v.config.QueriesDir)
+
+	info, err := fs.Stat(queriesFS, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			absolutePath := filepath.Join(v.config.QueriesDir, filepath.FromSlash(name))
+			return "", fmt.Sprintf("referenced SQL file %q not found at %q", relativePath, absolutePath)
+		}
+		return "", fmt.Sprintf("stat SQL file %q: %v", relativePath, err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Sprintf("referenced SQL file %q is not a regular file", relativePath)
+	}
+
+	queryBytes, err := fs.ReadFile(queriesFS, name)
+	if err != nil {
+		return "", fmt.Sprintf("read SQL file %q: %v", relativePath, err)
+	}
+	query := string(queryBytes)
+	if strings.TrimSpace(query) != "" {
+		if err := datasource.CheckStatement(query, v.config.AllowedStatements); err != nil {
+			return "", fmt.Sprintf("SQL file %q: %v", relativePath, err)
+		}
+	}
+
+	var undefined []string
+	for _, name := range datasource.QueryParamNames(query) {
+		if _, ok := v.config.Params[name]; !ok {
+			undefined = append(undefined, name)
+		}
+	}
+	if len(undefined) > 0 && v.config.BurstQuery != "" {
+		// The parameters may be columns of the burst driver query, which are only known once it runs. Without them
+		// the query can't be described either.
+		return "", ""
+	}
+	if len(undefined) > 0 {
+		return "", fmt.Sprintf("SQL file %q uses undefined parameters: %s", relativePath, strings.Join(undefined, ", "))
+	}
+	if strings.TrimSpace(query) == "" {
+		return "", "" // Empty queries are skipped during generation, there is nothing to describe.
+	}
+
+	return query, ""
+}
+
+// isKnownSource reports whether a reference's data source resolves like datasource.Registry.Lookup would.
+func (v *templateValidator) isKnownSource(name string) bool {
+	if name == "" {
+		return len(v.sourceNames) == 1 || slices.Contains(v.sourceNames, datasource.DefaultSourceName)
+	}
+	return slices.Contains(v.sourceNames, name)
+}
@@ -0,0 +1,140 @@
+package report_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{
+			"A1": "{{ .revenue }}", "B1": "{{ .revenue | printf \"%.2f\" }}", "D1": "revenue.sql",
+			"A2": "{{ .region ", "D2": "[table] missing.sql",
+			"A3": "{{ .sales }}", "D3": "../outside.sql",
+			"A4": "{{ .sales }}", "D4": "sales.sql",
+			"A5": "{{ .open_tickets }}", "D5": "ops:tickets.sql",
+			"A6": "{{ broken", // Rows without a reference are never processed.
+		},
+		map[string]string{
+			"revenue.sql": "SELECT revenue FROM r WHERE year = :year",
+			"sales.sql":   "SELECT sales FROM s WHERE region = :region AND year = :year",
+			"tickets.sql": "SELECT open_tickets",
+		},
+	)
+	cfg.Params = map[string]any{"year": int64(2026)}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	opts := report.ValidateOptions{SourceNames: []string{"warehouse", "default"}}
+	problems, err := report.ValidateTemplate(t.Context(), cfg, opts, logger)
+	require.NoError(t, err)
+
+	messages := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		messages = append(messages, problem.String())
+	}
+	require.Len(t, messages, 5)
+	assert.Contains(t, messages[0], `Sheet1!D2: referenced SQL file "missing.sql" not found`)
+	assert.Contains(t, messages[1], "Sheet1!A2: invalid template: parse cell template:")
+	assert.Contains(t, messages[2], `Sheet1!D3: SQL file "../outside.sql" is outside the queries directory`)
+	assert.Equal(t, `Sheet1!D4: SQL file "sales.sql" uses undefined parameters: region`, messages[3])
+	assert.Equal(t, `Sheet1!D5: unknown data source "ops"`, messages[4])
+}
+
+func TestValidateTemplate_Valid(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{"A1": "{{ .revenue }}", "D1": "revenue.sql", "A2": "{{ .revenue }}", "D2": "/revenue.sql"},
+		map[string]string{"revenue.sql": "SELECT revenue"},
+	)
+
+	// Without data source names, references aren't checked against the configured data sources.
+	problems, err := report.ValidateTemplate(
+		t.Context(),
+		cfg,
+		report.ValidateOptions{},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+// describingDataSource is a fakeDataSource that describes queries by their canned column names.
+type describingDataSource struct {
+	fakeDataSource
+	columns map[string][]string
+}
+
+func (d *describingDataSource) DescribeQuery(
+	_ context.Context,
+	query string,
+	_ map[string]any,
+) ([]datasource.Column, error) {
+	names, ok := d.columns[strings.TrimSpace(query)]
+	if !ok {
+		return nil, errors.New(`relation "missing" does not exist`)
+	}
+	columns := make([]datasource.Column, 0, len(names))
+	for _, name := range names {
+		columns = append(columns, datasource.Column{Name: name})
+	}
+	return columns, nil
+}
+
+func TestValidateTemplate_Schema(t *testing.T) {
+	t.Parallel()
+
+	cfg := testWorkspace(t,
+		map[string]any{
+			"A1": "{{ .region }}", "B1": "{{ .revenu | printf \"%.2f\" }}", "C1": "{{ $.params.year }}",
+			"D1": "revenue.sql",
+			"A2": "{{ if .Region }}{{ .region }}{{ end }}", "D2": "revenue.sql",
+			"A3": "{{ .count }}", "D3": "broken.sql",
+			"A4": "{{ .anything }}", "D4": "plain:other.sql", // Data source can't describe queries.
+		},
+		map[string]string{
+			"revenue.sql": "SELECT region, revenue FROM r WHERE year = :year",
+			"broken.sql":  "SELECT count(*) FROM missing",
+			"other.sql":   "SELECT 1",
+		},
+	)
+	cfg.Params = map[string]any{"year": int64(2026)}
+
+	source := &describingDataSource{columns: map[string][]string{
+		"SELECT region, revenue FROM r WHERE year = :year": {"region", "revenue"},
+	}}
+	sources := datasource.NewRegistry(map[string]datasource.DataSource{
+		datasource.DefaultSourceName: source,
+		"plain":                      &fakeDataSource{},
+	}, slog.New(slog.DiscardHandler))
+
+	problems, err := report.ValidateTemplate(
+		t.Context(),
+		cfg,
+		report.ValidateOptions{Sources: sources},
+		slog.New(slog.DiscardHandler),
+	)
+	require.NoError(t, err)
+
+	messages := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		messages = append(messages, problem.String())
+	}
+	assert.Equal(t, []string{
+		`Sheet1!B1 (revenue.sql): placeholder "revenu" matches no result column; did you mean "revenue"?`,
+		`Sheet1!A2 (revenue.sql): placeholder "Region" matches no result column; did you mean "region"?`,
+		`Sheet1!D3 (broken.sql): prepare query: relation "missing" does not exist`,
+	}, messages)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+// requestParams parses the report parameters of a request from its query string and, for POST requests, its form body.
+// Keys are parameter names, optionally with a type like the --param flag, e.g. "month:date=2026-10-01".
+func requestParams(r *http.Request) (map[string]any, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("%w: parse form: %w", report.ErrInvalidParam, err)
+	}
+
+	keys := slices.Sorted(maps.Keys(r.Form))
+	specs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range r.Form[key] {
+			specs = append(specs, key+"="+value)
+		}
+	}
+
+	return report.ParseParams(specs)
+}
+
+// mergeParams returns the configured parameters of a report overridden by those of a request.
+func mergeParams(configured, requested map[string]any) map[string]any {
+	merged := make(map[string]any, len(configured)+len(requested))
+	maps.Copy(merged, configured)
+	maps.Copy(merged, requested)
+	return merged
+}
@@ -0,0 +1,280 @@
+// Package server generates reports on demand over HTTP.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/nikoksr/assert-go"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+	"github.com/nikoksr/excalibur/internal/report"
+)
+
+const (
+	// xlsxContentType is the media type of generated reports.
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+	// IncompleteHeader is set to "true" on reports generated with failed rows, see report.OnErrorContinue.
+	IncompleteHeader = "X-Excalibur-Incomplete"
+
+	// healthTimeout bounds pinging the data sources for the health endpoint.
+	healthTimeout = 5 * time.Second
+
+	// readHeaderTimeout bounds reading request headers, so that slow clients can't hold connections open forever.
+	readHeaderTimeout = 10 * time.Second
+)
+
+var (
+	// ErrUnknownReport indicates a request for a report that isn't configured.
+	ErrUnknownReport = errors.New("unknown report")
+
+	// ErrBusy indicates that no report generation slot became free within the report's timeout.
+	ErrBusy = errors.New("too many reports being generated")
+)
+
+// Server generates the configured reports on request and streams them back. Reports are identified by their job name;
+// request parameters are merged into the report's configured parameters by name.
+//
+// Endpoints:
+//
+//	GET  /reports         Lists the names of all reports.
+//	GET  /reports/{name}  Generates a report. Parameters are passed like --param: "?region=NORTH&month:date=2026-10-01".
+//	POST /reports/{name}  Same, with the parameters in a form body.
+//	GET  /healthz         Pings the data sources.
+type Server struct {
+	sources *datasource.Registry
+	reports map[string]report.Job
+	names   []string      // Sorted.
+	slots   chan struct{} // Limits the number of reports generated at the same time.
+	logger  *slog.Logger
+}
+
+// New creates a server for the given reports. At most concurrency reports are generated at the same time; further
+// requests wait for a free slot within their report's timeout. Reports are generated in memory; their output paths are
+// never written.
+func New(sources *datasource.Registry, reports []report.Job, concurrency int, logger *slog.Logger) *Server {
+	assert.Assert(sources != nil, "data source registry must not be nil")
+	assert.Assert(concurrency > 0, "concurrency must be positive")
+	assert.Assert(logger != nil, "logger must not be nil")
+
+	s := &Server{
+		sources: sources,
+		reports: make(map[string]report.Job, len(reports)),
+		slots:   make(chan struct{}, concurrency),
+		logger:  logger.With(slog.String("component", "Server")),
+	}
+	for _, job := range reports {
+		s.reports[job.Name] = job
+		s.names = append(s.names, job.Name)
+	}
+	slices.Sort(s.names)
+
+	return s
+}
+
+// Handler returns the HTTP handler serving all endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /reports", s.handleList)
+	mux.HandleFunc("GET /reports/{name}", s.handleReport)
+	mux.HandleFunc("POST /reports/{name}", s.handleReport)
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	return mux
+}
+
+// Run serves HTTP requests on addr until ctx is done, then shuts down gracefully: requests in flight get as long as
+// the longest report timeout to finish.
+func (s *Server) Run(ctx context.Context, addr string) error {
+	assert.Assert(ctx != nil, "context must not be nil")
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen on %q: %w", addr, err)
+	}
+
+	httpServer := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		s.logger.Info("Serving reports", slog.String("addr", listener.Addr().String()), slog.Any("reports", s.names))
+		serveErr <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("serve HTTP: %w", err)
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("Shutting down, waiting for requests in flight...")
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.maxTimeout())
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shut down HTTP server: %w", err)
+	}
+	s.logger.Info("Server stopped")
+
+	return nil
+}
+
+func (s *Server) handleList(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"reports": s.names})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+	defer cancel()
+
+	if err := s.sources.Ping(ctx); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "unavailable", "error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	logger := s.logger.With(slog.String("report", name))
+
+	job, ok := s.reports[name]
+	if !ok {
+		s.writeError(w, logger, fmt.Errorf("%w %q", ErrUnknownReport, name))
+		return
+	}
+
+	params, err := requestParams(r)
+	if err != nil {
+		s.writeError(w, logger, err)
+		return
+	}
+	job.Config.Params = mergeParams(job.Config.Params, params)
+
+	// The timeout covers waiting for a slot as well, so that a request never takes longer than its report may.
+	ctx, cancel := context.WithTimeout(r.Context(), job.Config.Timeout)
+	defer cancel()
+
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		s.writeError(w, logger, fmt.Errorf("%w: no slot became free within %s", ErrBusy, job.Config.Timeout))
+		return
+	}
+	defer func() { <-s.slots }()
+
+	logger.Info("Generating report", slog.Any("params", params))
+	startTime := time.Now()
+
+	// The report is only sent once it has been generated: the generator writes nothing if generation fails, which
+	// leaves the response free for an error status.
+	resp := &reportResponse{w: w, name: job.Name}
+	err = s.generate(ctx, job, resp, logger)
+	incomplete := errors.Is(err, report.ErrReportIncomplete)
+	if err != nil && !incomplete {
+		if resp.sent {
+			logger.Warn("Failed to send report", slog.String("error", err.Error())) // Likely the client went away.
+			return
+		}
+		s.writeError(w, logger, err)
+		return
+	}
+
+	logger.Info("Report sent", slog.Bool("incomplete", incomplete), slog.Duration("duration", time.Since(startTime)))
+}
+
+// generate validates the report's configuration, including the request's parameters, and writes the report to resp.
+func (s *Server) generate(ctx context.Context, job report.Job, resp *reportResponse, logger *slog.Logger) error {
+	if err := report.ValidateJob(ctx, job); err != nil {
+		return err
+	}
+
+	templateFile, err := os.Open(job.Config.TemplatePath)
+	if err != nil {
+		return fmt.Errorf("open template file: %w", err)
+	}
+	defer templateFile.Close()
+
+	generator := report.NewGenerator(s.sources, job.Config, logger)
+	resp.incomplete = generator.Incomplete
+	return generator.Generate(ctx, templateFile, resp)
+}
+
+// reportResponse writes a generated report to the client, sending its headers with the first write. The workbook is
+// held in memory while it is generated, like every workbook, but written straight to the client once it is done.
+type reportResponse struct {
+	w          http.ResponseWriter
+	name       string
+	incomplete func() bool // Whether rows failed; final once the workbook is written.
+	sent       bool        // Whether the headers were sent.
+}
+
+func (r *reportResponse) Write(p []byte) (int, error) {
+	if !r.sent {
+		r.sent = true
+		r.w.Header().Set("Content-Type", xlsxContentType)
+		r.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": r.name + ".xlsx",
+		}))
+		if r.incomplete != nil && r.incomplete() {
+			r.w.Header().Set(IncompleteHeader, "true")
+		}
+		r.w.WriteHeader(http.StatusOK)
+	}
+	return r.w.Write(p)
+}
+
+// maxTimeout returns the longest timeout of all reports.
+func (s *Server) maxTimeout() time.Duration {
+	var longest time.Duration
+	for _, job := range s.reports {
+		longest = max(longest, job.Config.Timeout)
+	}
+	return longest
+}
+
+// writeError logs err and writes it as a JSON error response with a status matching its cause.
+func (s *Server) writeError(w http.ResponseWriter, logger *slog.Logger, err error) {
+	status := statusOf(err)
+	if status >= http.StatusInternalServerError {
+		logger.Error("Report request failed", slog.Int("status", status), slog.String("error", err.Error()))
+	} else {
+		logger.Warn("Report request rejected", slog.Int("status", status), slog.String("error", err.Error()))
+	}
+	writeJSON(w, status, map[string]any{"error": err.Error()})
+}
+
+// statusOf maps an error to the HTTP status of its cause.
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrUnknownReport):
+		return http.StatusNotFound
+	case errors.Is(err, report.ErrInvalidParam),
+		errors.Is(err, report.ErrInvalidJob), // Reports are validated on startup, so only parameters can break them.
+		errors.Is(err, datasource.ErrUndefinedParameter):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrBusy):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body) // The client went away; there's no one left to tell.
+}
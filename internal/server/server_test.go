@@ -0,0 +1,230 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/nikoksr/excalibur/internal/datasource"
+	"github.com/nikoksr/excalibur/internal/report"
+	"github.com/nikoksr/excalibur/internal/server"
+)
+
+// fakeDataSource returns the sales of the region parameter. Queries block until ctx is done if slow is set and fail
+// with fetchErr if it is set.
+type fakeDataSource struct {
+	slow     bool
+	fetchErr error
+	pingErr  error
+}
+
+func (f *fakeDataSource) FetchData(ctx context.Context, _ string, params map[string]any) (map[string]any, error) {
+	if f.slow {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if f.fetchErr != nil {
+		return nil, f.fetchErr
+	}
+	sales := map[string]int{"NORTH": 42, "SOUTH": 7}
+	return map[string]any{"sales": sales[params["region"].(string)]}, nil
+}
+
+func (f *fakeDataSource) FetchRows(_ context.Context, _ string, _ map[string]any) ([]map[string]any, error) {
+	return nil, nil
+}
+
+func (f *fakeDataSource) Ping(_ context.Context) error { return f.pingErr }
+
+func (f *fakeDataSource) Close(_ context.Context) error { return nil }
+
+// newTestServer serves a "sales" report whose single cell shows the sales of the region parameter, NORTH by default.
+// The report continues on failed rows if the data source fails its queries.
+func newTestServer(t *testing.T, source *fakeDataSource, timeout time.Duration) *httptest.Server {
+	t.Helper()
+
+	baseDir := t.TempDir()
+	queriesDir := filepath.Join(baseDir, "queries")
+	require.NoError(t, os.Mkdir(queriesDir, 0o750))
+	query := "SELECT sales FROM s WHERE region = :region"
+	require.NoError(t, os.WriteFile(filepath.Join(queriesDir, "sales.sql"), []byte(query), 0o600))
+
+	f := excelize.NewFile()
+	defer f.Close()
+	require.NoError(t, f.SetCellValue("Sheet1", "A1", "{{ .sales }}"))
+	require.NoError(t, f.SetCellValue("Sheet1", "D1", "sales.sql"))
+	templatePath := filepath.Join(baseDir, "template.xlsx")
+	require.NoError(t, f.SaveAs(templatePath))
+
+	job := report.Job{Name: "sales", Config: report.Config{
+		TemplatePath:        templatePath,
+		DataSourceRefColumn: "D",
+		QueriesDir:          queriesDir,
+		OutputPath:          filepath.Join(baseDir, "sales.xlsx"),
+		Timeout:             timeout,
+		Params:              map[string]any{"region": "NORTH"},
+	}}
+	if source.fetchErr != nil {
+		job.Config.OnError = report.OnErrorContinue
+	}
+	require.NoError(t, report.ValidateJob(t.Context(), job))
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sources := datasource.NewRegistry(map[string]datasource.DataSource{datasource.DefaultSourceName: source}, logger)
+	srv := httptest.NewServer(server.New(sources, []report.Job{job}, 2, logger).Handler())
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func get(t *testing.T, rawURL string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, rawURL, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, body
+}
+
+// salesCell returns the value of the cell the sales are written to.
+func salesCell(t *testing.T, body []byte) string {
+	t.Helper()
+
+	f, err := excelize.OpenReader(bytes.NewReader(body))
+	require.NoError(t, err)
+	defer f.Close()
+
+	value, err := f.GetCellValue("Sheet1", "A1")
+	require.NoError(t, err)
+	return value
+}
+
+func TestServer_Report(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, &fakeDataSource{}, time.Minute)
+
+	resp, body := get(t, srv.URL+"/reports/sales")
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=sales.xlsx`, resp.Header.Get("Content-Disposition"))
+	assert.Equal(t, "42", salesCell(t, body))
+
+	resp, body = get(t, srv.URL+"/reports/sales?region=SOUTH")
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, "7", salesCell(t, body))
+
+	form := url.Values{"region": {"SOUTH"}}
+	req, err := http.NewRequestWithContext(
+		t.Context(), http.MethodPost, srv.URL+"/reports/sales", strings.NewReader(form.Encode()),
+	)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	postResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer postResp.Body.Close()
+	body, err = io.ReadAll(postResp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, postResp.StatusCode, string(body))
+	assert.Equal(t, "7", salesCell(t, body))
+}
+
+func TestServer_ReportIncomplete(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, &fakeDataSource{fetchErr: errors.New("connection reset")}, time.Minute)
+
+	resp, body := get(t, srv.URL+"/reports/sales")
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, "true", resp.Header.Get(server.IncompleteHeader))
+	assert.Equal(t, report.DefaultErrorMarker, salesCell(t, body))
+}
+
+func TestServer_ReportErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		path       string
+		slow       bool
+		wantStatus int
+		wantError  string
+	}{
+		{name: "Unknown Report", path: "/reports/missing", wantStatus: http.StatusNotFound, wantError: "unknown report"},
+		{
+			name:       "Invalid Param",
+			path:       "/reports/sales?month:date=October",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid report parameter",
+		},
+		{
+			name:       "Param Specified Twice",
+			path:       "/reports/sales?region=NORTH&region=SOUTH",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "more than once",
+		},
+		{name: "Timeout", path: "/reports/sales", slow: true, wantStatus: http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := newTestServer(t, &fakeDataSource{slow: tt.slow}, 100*time.Millisecond)
+
+			resp, body := get(t, srv.URL+tt.path)
+			require.Equal(t, tt.wantStatus, resp.StatusCode, string(body))
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+			var errBody struct {
+				Error string `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(body, &errBody))
+			assert.Contains(t, errBody.Error, tt.wantError)
+		})
+	}
+}
+
+func TestServer_List(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, &fakeDataSource{}, time.Minute)
+
+	resp, body := get(t, srv.URL+"/reports")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"reports": ["sales"]}`, string(body))
+}
+
+func TestServer_Health(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, &fakeDataSource{}, time.Minute)
+	resp, body := get(t, srv.URL+"/healthz")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"status": "ok"}`, string(body))
+
+	srv = newTestServer(t, &fakeDataSource{pingErr: errors.New("connection refused")}, time.Minute)
+	resp, body = get(t, srv.URL+"/healthz")
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.JSONEq(t, `{"status": "unavailable", "error": "data source \"default\": connection refused"}`, string(body))
+}